@@ -1,11 +1,19 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"yzc/internal"
 )
 
@@ -13,23 +21,248 @@ var logger = log.Default()
 
 const sourceSuffix = ".yz"
 
+const usage = `Usage: yzc <command> [flags] <source roots...>
+
+Commands:
+  build    compile the Yz sources found in the source roots
+  run      compile the Yz sources found in a source root and execute the program
+  check    validate the Yz sources found in the source roots without compiling them
+
+Run "yzc <command> -h" for the flags of a command.
+`
+
 func main() {
-	//files := collectSourceFiles("phantom")
-	//files := collectSourceFiles("README.md")
-	//files := collectSourceFiles( ".", "examples/simple")
-	//files := collectSourceFiles(".")
-	files := collectSourceFiles("examples/simple")
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+	switch command {
+	case "build":
+		build(args)
+	case "run":
+		run(args)
+	case "check":
+		check(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "yzc: unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+// build parses the flags of the build command, collects the source files from the
+// source roots given as arguments and compiles them.
+func build(args []string) {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	options := internal.DefaultBuildOptions()
+	flags.StringVar(&options.TargetDir, "o", options.TargetDir, "output `directory` for the compiled binaries")
+	flags.StringVar(&options.GeneratedDir, "generated-dir", options.GeneratedDir, "`directory` where the generated Go sources are kept")
+	flags.BoolVar(&options.KeepGeneratedSource, "keep-generated", options.KeepGeneratedSource, "keep the generated Go sources after the build")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: yzc build [flags] <source roots...>\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	files := collectSourceFiles(flags.Args()...)
 	logger.Printf("Collecting source files:\n")
 	for _, f := range files {
 		logger.Printf("%v", f)
 	}
-	internal.Build(files, true)
+	result := internal.Build(files, options)
+	if reportDiagnostics(result.Diagnostics) {
+		os.Exit(1)
+	}
+}
+
+// run compiles the sources under a single source root into a cache directory and executes the
+// resulting binary. The arguments after "--" are passed to the program, which inherits
+// stdin, stdout and stderr. yzc exits with the exit code of the program.
+func run(args []string) {
+	ra, e := parseRunArgs(args)
+	if errors.Is(e, flag.ErrHelp) {
+		os.Exit(0)
+	} else if e != nil {
+		os.Exit(2)
+	}
+	if !ra.verbose {
+		logger.SetOutput(io.Discard)
+	}
+
+	cacheDir, e := runCacheDir(ra.root)
+	if e != nil {
+		fmt.Fprintf(os.Stderr, "yzc: %v\n", e)
+		os.Exit(1)
+	}
+	// Start from an empty directory so a failed build never runs a stale binary.
+	_ = os.RemoveAll(cacheDir)
+	options := internal.DefaultBuildOptions()
+	options.TargetDir = cacheDir
+	options.KeepGeneratedSource = false
+
+	result := internal.Build(collectSourceFiles(ra.root), options)
+	if reportDiagnostics(result.Diagnostics) {
+		os.Exit(1)
+	}
+	binary, e := selectBinary(result.Binaries, ra.mainName)
+	if e != nil {
+		fmt.Fprintf(os.Stderr, "yzc: %v\n", e)
+		os.Exit(1)
+	}
+
+	cmd := exec.Command(binary, ra.programArgs...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if code, e := exitCode(cmd.Run()); e != nil {
+		fmt.Fprintf(os.Stderr, "yzc: %v\n", e)
+		os.Exit(1)
+	} else if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// runArgs are the arguments of the run command.
+type runArgs struct {
+	root        string
+	mainName    string
+	verbose     bool
+	programArgs []string // the arguments passed to the program, the ones after the source root
+}
+
+// parseRunArgs parses the flags and the arguments of the run command. The flags end at the source
+// root, the arguments after it are the arguments of the program, an optional "--" before them is
+// dropped. The usage is printed if the arguments are invalid.
+func parseRunArgs(args []string) (runArgs, error) {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	ra := runArgs{}
+	flags.StringVar(&ra.mainName, "main", "main", "`name` of the source file (without "+sourceSuffix+") to execute when the root has several")
+	flags.BoolVar(&ra.verbose, "v", false, "log the compilation steps")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: yzc run [flags] <source root> [-- args...]\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	if e := flags.Parse(args); e != nil {
+		return ra, e
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return ra, errors.New("missing the source root")
+	}
+	ra.root, ra.programArgs = flags.Arg(0), flags.Args()[1:]
+	if len(ra.programArgs) > 0 && ra.programArgs[0] == "--" {
+		ra.programArgs = ra.programArgs[1:]
+	}
+	return ra, nil
+}
+
+// exitCode returns the exit code of a program from the error of its cmd.Run: 0 if it succeeded, its
+// exit code if it failed and, if it was killed by a signal, 128 plus the number of the signal, like
+// the shells do. The error returned is the one of a program that couldn't be started.
+func exitCode(runError error) (int, error) {
+	if runError == nil {
+		return 0, nil
+	}
+	var exitError *exec.ExitError
+	if !errors.As(runError, &exitError) {
+		return 0, runError
+	}
+	if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), nil
+	}
+	return exitError.ExitCode(), nil
+}
+
+// check runs the front end over the sources in the source roots, prints every error found
+// and exits with status 1 if there is any.
+func check(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "log the files being checked")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: yzc check [flags] <source roots...>\n\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if !*verbose {
+		logger.SetOutput(io.Discard)
+	}
+
+	if reportDiagnostics(internal.Check(collectSourceFiles(flags.Args()...))) {
+		os.Exit(1)
+	}
+}
+
+// reportDiagnostics prints every diagnostic on stderr with its source excerpt and returns true if any
+// of them is an error. The output is colored when stderr is a terminal.
+func reportDiagnostics(diagnostics *internal.Diagnostics) bool {
+	fmt.Fprint(os.Stderr, diagnostics.Render(useColor(os.Stderr)))
+	return diagnostics.HasErrors()
+}
+
+// useColor returns true if f is a terminal and the NO_COLOR environment variable
+// (https://no-color.org) is not set.
+func useColor(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, e := f.Stat()
+	return e == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runCacheDir returns the directory under the user cache where the binaries of the
+// given source root are placed. Each source root gets its own directory.
+func runCacheDir(root string) (string, error) {
+	userCache, e := os.UserCacheDir()
+	if e != nil {
+		return "", e
+	}
+	absoluteRoot, e := filepath.Abs(root)
+	if e != nil {
+		return "", e
+	}
+	sum := sha256.Sum256([]byte(absoluteRoot))
+	return filepath.Join(userCache, "yzc", "run", hex.EncodeToString(sum[:8])), nil
+}
+
+// selectBinary picks the binary to execute: the only one built or the one called mainName.
+func selectBinary(candidates []string, mainName string) (string, error) {
+	switch {
+	case len(candidates) == 0:
+		return "", fmt.Errorf("nothing to run: no binary was produced")
+	case len(candidates) == 1:
+		return candidates[0], nil
+	}
+	for _, b := range candidates {
+		if filepath.Base(b) == mainName {
+			return b, nil
+		}
+	}
+	return "", fmt.Errorf("several programs were built and none is called %q: %s\n"+
+		"Hint: use -main to select the program to run", mainName, strings.Join(candidates, ", "))
+}
 
+// collectSourceFiles returns the source files found in the source roots. If the source roots are
+// invalid the problem is reported like the other diagnostics and the program terminates.
+func collectSourceFiles(sourceRoots ...string) []internal.SourceFile {
+	files, e := sourceFiles(sourceRoots...)
+	if e != nil {
+		fmt.Fprint(os.Stderr, e.Render(useColor(os.Stderr)))
+		os.Exit(1)
+	}
+	return files
 }
 
-// collectSourceFiles walks through the provided source directories and collects all source files
-// with the specified suffix. It returns a slice of SourceFile structs representing the collected files.
-// If any errors occur during the directory walk, the function logs the error and terminates the program.
+// sourceFiles walks through the provided source directories and collects all source files
+// with the specified suffix. It returns a slice of SourceFile structs representing the collected files,
+// or a diagnostic with the first problem found during the directory walk.
 //
 // The following validations are performed:
 // - The sourceRoots are valid directories.
@@ -40,22 +273,22 @@ func main() {
 //
 // Returns:
 // - []internal.SourceFile: a slice of SourceFile structs representing the collected source files.
-func collectSourceFiles(sourceRoots ...string) []internal.SourceFile {
+// - *internal.Diagnostic: the problem found in the source roots, nil if there is none.
+func sourceFiles(sourceRoots ...string) ([]internal.SourceFile, *internal.Diagnostic) {
 	var files []internal.SourceFile
 	seen := make(map[string]internal.SourceFile)
+	sourcePath := strings.Join(sourceRoots, ", ")
 	for _, currentRoot := range sourceRoots {
 		//logger.Printf("Walking source directory: %s", currentRoot)
 		walkError := filepath.WalkDir(currentRoot, func(path string, info fs.DirEntry, err error) error {
 
 			if err != nil {
-				return fmt.Errorf("Error while reading source directory: %v\n"+
-					"Source path: %s\n"+
-					"Hint: Check all the directories in the source path exists.", err, sourceRoots)
+				return internal.NewSourceError(path, fmt.Sprintf("cannot read the source directory: %v", err),
+					"check all the directories in the source path exist. Source path: "+sourcePath)
 			}
 			if info == nil || path == currentRoot && !info.IsDir() {
-				return fmt.Errorf("Not a directory: %s\n"+
-					"Hint: Check all the directories in the source path exists. Source path: %s", path, sourceRoots)
-
+				return internal.NewSourceError(path, "not a directory",
+					"check all the directories in the source path exist. Source path: "+sourcePath)
 			}
 
 			if strings.HasSuffix(path, sourceSuffix) && !info.IsDir() {
@@ -78,16 +311,22 @@ func collectSourceFiles(sourceRoots ...string) []internal.SourceFile {
 					seen[afp] = internal.NewSourceFile(currentRoot, path, afp)
 				} else {
 					first := seen[afp]
-					return fmt.Errorf("Duplicate source files\n%s (source directory:\"%s\") and %s (souce directory:\"%s\") are the same file: %s\n"+
-						"Hint: Check a source directory is not a subdirectory of another source directory. Source directories: %s ", first.Path, first.Root, file.Path, file.Root, afp, sourceRoots)
+					return internal.NewSourceError(afp,
+						fmt.Sprintf("duplicate source files, %s (source directory \"%s\") and %s (source directory \"%s\") are the same file",
+							first.Path, first.Root, file.Path, file.Root),
+						"check a source directory is not a subdirectory of another source directory. Source directories: "+sourcePath)
 				}
 				files = append(files, file)
 			}
 			return nil
 		})
 		if walkError != nil {
-			logger.Fatalf("%v", walkError)
+			var d *internal.Diagnostic
+			if !errors.As(walkError, &d) {
+				d = internal.NewSourceError(currentRoot, walkError.Error(), "")
+			}
+			return nil, d
 		}
 	}
-	return files
+	return files, nil
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRunArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    runArgs
+		wantErr bool
+	}{
+		{
+			name: "Source root",
+			args: []string{"examples"},
+			want: runArgs{root: "examples", mainName: "main", programArgs: []string{}},
+		},
+		{
+			name: "Program arguments after --",
+			args: []string{"-v", "examples", "--", "-v", "a"},
+			want: runArgs{root: "examples", mainName: "main", verbose: true, programArgs: []string{"-v", "a"}},
+		},
+		{
+			name: "Program arguments without --",
+			args: []string{"examples", "a", "--", "b"},
+			want: runArgs{root: "examples", mainName: "main", programArgs: []string{"a", "--", "b"}},
+		},
+		{
+			name: "Main",
+			args: []string{"-main", "factorial", "examples", "--"},
+			want: runArgs{root: "examples", mainName: "factorial", programArgs: []string{}},
+		},
+		{
+			name:    "Missing source root",
+			args:    []string{"-v"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown flag",
+			args:    []string{"-x", "examples"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRunArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRunArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRunArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectBinary(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		mainName   string
+		want       string
+		wantErr    bool
+	}{
+		{name: "Only one", candidates: []string{"target/hello"}, mainName: "main", want: "target/hello"},
+		{name: "Main name", candidates: []string{"target/hello", "target/main"}, mainName: "main", want: "target/main"},
+		{name: "Selected with -main", candidates: []string{"target/hello", "target/main"}, mainName: "hello", want: "target/hello"},
+		{name: "None called like main", candidates: []string{"target/hello", "target/bye"}, mainName: "main", wantErr: true},
+		{name: "Nothing built", mainName: "main", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectBinary(tt.candidates, tt.mainName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectBinary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("selectBinary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	tests := []struct {
+		name   string
+		script string
+		want   int
+	}{
+		{name: "Success", script: "exit 0", want: 0},
+		{name: "Exit code", script: "exit 3", want: 3},
+		{name: "Killed by a signal", script: "kill -KILL $$", want: 128 + 9},
+		{name: "Terminated by a signal", script: "kill -TERM $$", want: 128 + 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := exitCode(exec.Command("sh", "-c", tt.script).Run())
+			if err != nil || got != tt.want {
+				t.Errorf("exitCode() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := exitCode(exec.Command("./does-not-exist").Run()); err == nil {
+		t.Errorf("exitCode() of a program that can't be started, error = nil")
+	}
+}
+
+func TestSourceFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.yz"), []byte("a: 1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	files, d := sourceFiles(dir)
+	if d != nil || len(files) != 1 || files[0].Path != "main.yz" {
+		t.Fatalf("sourceFiles() = %v, %v, want main.yz", files, d)
+	}
+
+	tests := []struct {
+		name  string
+		roots []string
+		want  string
+	}{
+		{
+			name:  "Missing directory",
+			roots: []string{filepath.Join(dir, "missing")},
+			want:  "error[E0001]: cannot read the source directory",
+		},
+		{
+			name:  "Not a directory",
+			roots: []string{filepath.Join(dir, "main.yz")},
+			want:  "error[E0001]: not a directory",
+		},
+		{
+			name:  "Duplicate source files",
+			roots: []string{dir, dir},
+			want:  "error[E0001]: duplicate source files",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, d := sourceFiles(tt.roots...)
+			if d == nil {
+				t.Fatalf("sourceFiles() diagnostic = nil, want %q", tt.want)
+			}
+			if got := d.Render(false); !strings.HasPrefix(got, tt.want) || !strings.Contains(got, "= hint:") {
+				t.Errorf("sourceFiles() diagnostic = \n%s\nwant it to start with %q and have a hint", got, tt.want)
+			}
+		})
+	}
+}
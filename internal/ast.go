@@ -2,6 +2,9 @@ package internal
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 )
 
 type (
@@ -16,53 +19,276 @@ type (
 	}
 
 	Boc struct {
+		span        span
 		expressions []expression
 		statements  []statement
 	}
 
 	BasicLit struct {
-		pos       position
+		span      span
 		tt        tokenType
 		val       string
 		basicType Type
 	}
+	// InterpolatedString is a string literal with placeholders: "Hello, `name`!". The literal text
+	// is split by the placeholders, there is a part before, between and after them.
+	InterpolatedString struct {
+		span   span
+		parts  []string
+		values []expression // the expressions of the placeholders, one less than parts
+	}
 	ArrayLit struct {
-		pos         position
+		span        span
 		expressions []expression
 		arrayType   *ArrayType
 	}
 	// DictLit represents a dictionary literal [k1:v1 k2:v2] or [String]Int for empty dictionary
 	DictLit struct {
-		pos      position
+		span     span
 		dictType *DictType
 		keys     []expression
 		values   []expression
 	}
 
 	ShortDeclaration struct {
-		pos      position
+		span     span
 		variable *Variable
 		value    expression
 	}
 
 	KeyValue struct {
-		pos position
-		key expression
-		val expression
+		span span
+		key  expression
+		val  expression
+	}
+
+	// Invocation is the call of a boc with positional arguments foo(1, 2) or named arguments
+	// foo(x: 1, y: 2). The callee can be any expression, including another invocation: foo()().
+	Invocation struct {
+		span      span
+		callee    expression
+		args      []expression
+		namedArgs []*NamedArg
+	}
+	// MemberAccess is a variable of a receiver, the callee of a method invocation: `a + 1` invokes
+	// the member `+` of `a`.
+	MemberAccess struct {
+		span     span
+		receiver expression
+		member   *Variable
+	}
+	// TypeInstantiation creates a value of a user-defined type or of a case of a variant with the
+	// values of its members, passed in order or by name: Point(1, 2) or Point(x: 1, y: 2).
+	TypeInstantiation struct {
+		span     span
+		typeName *Variable
+		instType *BocType    // the type created, the instance with the type arguments inferred of a generic type
+		values   []*NamedArg // the members passed, by name
+		defaults []*NamedArg // the members not passed, with the value they have in the type declaration
+	}
+	// NamedArg is an argument passed by name in an invocation.
+	NamedArg struct {
+		span  span
+		name  string
+		value expression
+	}
+
+	// Assignment sets new values to existing variables: a = 1 or a, b = b, a.
+	// Its value is the value assigned, a tuple of them when there are several variables.
+	Assignment struct {
+		span      span
+		variables []*Variable
+		values    []expression
+	}
+
+	// Index reads an element of an array or a dictionary: a[0] or d["key"]. Reading a dictionary
+	// results in an Option, the key may not be in it.
+	Index struct {
+		span      span
+		receiver  expression
+		index     expression
+		indexType Type
+	}
+	// IndexAssignment sets an element of an array or a dictionary: a[0] = 1, d["key"] = 1 or d["key": 1].
+	IndexAssignment struct {
+		span   span
+		target *Index
+		value  expression
 	}
 
 	ParenthesisExp struct {
-		lparen      position
+		lparen      span
 		expressions []expression
-		rparen      position
+		rparen      span
+	}
+	// VariableDeclaration declares variables with an explicit type: a Int. With values it also
+	// defines them: a Int = 1 or a Int, b String = 1, "b".
+	VariableDeclaration struct {
+		span      span
+		variables []*Variable
+		values    []expression // empty when the variables are only declared
+	}
+	// TypeDeclaration declares a user-defined type with a block signature and an optional body:
+	// Point #(x Int, y Int) = {...}, or with the variables of a block literal: Point: {x: 1, y: 2}.
+	// The type of the variable is the BocType of the new type.
+	TypeDeclaration struct {
+		span     span
+		variable *Variable
+		body     *Boc // nil when the type only has a signature
+	}
+	// VariantDeclaration declares a variant type and its cases: Option { Some(value T), None() }.
+	// The type of the variable is the VariantType, the cases are variables that construct its values.
+	VariantDeclaration struct {
+		span     span
+		variable *Variable
+		cases    []*Variable
+	}
+	// When evaluates the body of the first case whose condition is true:
+	// when { n == 0 => 1 }, { _ => n }. Its value is the value of that body, whenType is the
+	// type shared by the bodies of all the cases.
+	When struct {
+		span     span
+		cases    []*WhenCase
+		whenType Type
+	}
+	// WhenCase is a condition and the body evaluated if it's true, the condition is nil for the
+	// default case `_`.
+	WhenCase struct {
+		span      span
+		condition expression
+		body      *Boc
+	}
+	// Match evaluates the body of the first case whose type is the type of the value:
+	// opt match { Some => opt.value }, { _ => 0 }. When the value is a variable, it has the type
+	// of the case inside its body. matchType is the type shared by the bodies.
+	Match struct {
+		span      span
+		value     expression
+		cases     []*MatchCase
+		matchType Type
+	}
+	// MatchCase is a type and the body evaluated if the value has it, the type is nil for the
+	// default case `_`.
+	MatchCase struct {
+		span    span
+		pattern *Variable // the name of the type matched and the type
+		body    *Boc
+	}
+	// Return ends the evaluation of the boc it's in, its values are the result of the boc: return a, b.
+	Return struct {
+		span   span
+		values []expression // empty when it only ends the boc
+	}
+	// Break ends the loop of the boc it's in.
+	Break struct {
+		span span
+	}
+	// Continue ends the current iteration of the loop of the boc it's in.
+	Continue struct {
+		span span
+	}
+	// BadExpr is a placeholder for an expression with syntax errors, span is the source skipped.
+	BadExpr struct {
+		span span
+	}
+	// BadStmt is a placeholder for a statement with syntax errors, span is the source skipped.
+	BadStmt struct {
+		span span
 	}
 	Variable struct {
-		pos     position
+		span    span
 		name    string // empty name means only return type is expressed, single uppercase name generic
 		varType Type
 	}
 )
 
+// nodes returns the expressions and statements of a block in the order they appear in the source.
+func (boc *Boc) nodes() []interface{} {
+	nodes := make([]interface{}, 0, len(boc.expressions)+len(boc.statements))
+	for _, e := range boc.expressions {
+		nodes = append(nodes, e)
+	}
+	for _, s := range boc.statements {
+		nodes = append(nodes, s)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return spanOf(nodes[i]).start < spanOf(nodes[j]).start
+	})
+	return nodes
+}
+
+// loopOf returns the condition and the body of a loop: while({ i < 10 }, { i = i + 1 }) evaluates
+// the body while the condition is true. ok is false if the invocation is not a loop.
+func loopOf(inv *Invocation) (condition, body *Boc, ok bool) {
+	v, ok := inv.callee.(*Variable)
+	if !ok || v.name != "while" || len(inv.args) != 2 || len(inv.namedArgs) != 0 {
+		return nil, nil, false
+	}
+	condition, ok = inv.args[0].(*Boc)
+	if !ok {
+		return nil, nil, false
+	}
+	body, ok = inv.args[1].(*Boc)
+	return condition, body, ok
+}
+
+// spanOf returns the source range of an expression or a statement.
+func spanOf(node interface{}) span {
+	switch e := node.(type) {
+	case *Boc:
+		return e.span
+	case *BasicLit:
+		return e.span
+	case *InterpolatedString:
+		return e.span
+	case *ArrayLit:
+		return e.span
+	case *DictLit:
+		return e.span
+	case *ShortDeclaration:
+		return e.span
+	case *KeyValue:
+		return e.span
+	case *Invocation:
+		return e.span
+	case *MemberAccess:
+		return e.span
+	case *TypeInstantiation:
+		return e.span
+	case *Assignment:
+		return e.span
+	case *Index:
+		return e.span
+	case *IndexAssignment:
+		return e.span
+	case *ParenthesisExp:
+		return span{e.lparen.file, e.lparen.start, e.rparen.end}
+	case *BadExpr:
+		return e.span
+	case *Variable:
+		return e.span
+	case *When:
+		return e.span
+	case *Match:
+		return e.span
+	case *VariableDeclaration:
+		return e.span
+	case *TypeDeclaration:
+		return e.span
+	case *VariantDeclaration:
+		return e.span
+	case *Return:
+		return e.span
+	case *Break:
+		return e.span
+	case *Continue:
+		return e.span
+	case *BadStmt:
+		return e.span
+	}
+	return span{}
+}
+
 func (k *KeyValue) String() string {
 	return prettyPrint(k, 0)
 }
@@ -75,6 +301,366 @@ func (k *KeyValue) dataType() Type {
 	return k.val.dataType()
 }
 
+func (inv *Invocation) String() string {
+	return prettyPrint(inv, 0)
+}
+
+func (inv *Invocation) stringValue() string {
+	args := make([]string, 0, len(inv.args)+len(inv.namedArgs))
+	for _, a := range inv.args {
+		args = append(args, a.stringValue())
+	}
+	for _, na := range inv.namedArgs {
+		args = append(args, na.name+": "+na.value.stringValue())
+	}
+	return fmt.Sprintf("%s(%s)", inv.callee.stringValue(), strings.Join(args, ", "))
+}
+
+// dataType is the result type of the callee, or TBD until it is known. The type parameters of a
+// generic block take the types of the arguments: id #(T, x T, T) invoked with 1 returns an Int.
+func (inv *Invocation) dataType() Type {
+	bt, ok := inv.callee.dataType().(*BocType)
+	if !ok || bt.result == nil {
+		return newTBD()
+	}
+	if len(bt.typeParams) == 0 {
+		return bt.result
+	}
+	return inv.typeArgs(bt).substitute(bt.result)
+}
+
+// typeArgs returns the types the arguments give to the type parameters of the generic block bt,
+// the ones that can't be inferred are TBD.
+func (inv *Invocation) typeArgs(bt *BocType) bindings {
+	b := newBindings(bt.typeParams)
+	var params []*Variable
+	for _, v := range bt.variables {
+		if v.name != "" {
+			params = append(params, v)
+		}
+	}
+	for i, a := range inv.args {
+		if i < len(params) {
+			b.infer(params[i].varType, a.dataType())
+		}
+	}
+	for _, na := range inv.namedArgs {
+		for _, v := range params {
+			if v.name == na.name {
+				b.infer(v.varType, na.value.dataType())
+			}
+		}
+	}
+	return b.resolve()
+}
+
+func (ti *TypeInstantiation) String() string {
+	return prettyPrint(ti, 0)
+}
+
+func (ti *TypeInstantiation) stringValue() string {
+	values := make([]string, len(ti.values))
+	for i, v := range ti.values {
+		values[i] = v.name + ": " + v.value.stringValue()
+	}
+	return ti.typeName.name + "(" + strings.Join(values, ", ") + ")"
+}
+
+// dataType returns the type instantiated, or the variant of the case instantiated.
+func (ti *TypeInstantiation) dataType() Type {
+	if vt, ok := ti.instType.result.(*VariantType); ok && isCaseOf(ti.instType, vt) {
+		return vt
+	}
+	return ti.instType
+}
+
+// complete takes the type arguments that can't be inferred from the values of the members from t,
+// the type of the variable the value is assigned to: b Option(Int) = None().
+func (ti *TypeInstantiation) complete(t Type) {
+	declaration := ti.typeName.varType.(*BocType)
+	params := typeParamsOf(declaration)
+	var inferred, args []Type
+	switch t := t.(type) {
+	case *VariantType:
+		if vt, ok := ti.dataType().(*VariantType); ok && vt.name == t.name {
+			inferred, args = vt.typeArgs, t.typeArgs
+		}
+	case *BocType:
+		if ti.instType.name == t.name {
+			inferred, args = ti.instType.typeArgs, t.typeArgs
+		}
+	}
+	if len(args) != len(params) || !slices.ContainsFunc(inferred, isTBD) {
+		return
+	}
+	b := bindings{}
+	for i, tp := range params {
+		b[tp] = args[i]
+	}
+	ti.instType = b.substitute(declaration).(*BocType)
+}
+
+func (ma *MemberAccess) String() string {
+	return prettyPrint(ma, 0)
+}
+
+func (ma *MemberAccess) stringValue() string {
+	return ma.receiver.stringValue() + "." + ma.member.name
+}
+
+func (ma *MemberAccess) dataType() Type {
+	return ma.member.dataType()
+}
+
+func (na *NamedArg) String() string {
+	return prettyPrint(na, 0)
+}
+
+func (as *Assignment) String() string {
+	return prettyPrint(as, 0)
+}
+
+func (as *Assignment) stringValue() string {
+	names := make([]string, len(as.variables))
+	for i, v := range as.variables {
+		names[i] = v.name
+	}
+	values := make([]string, len(as.values))
+	for i, v := range as.values {
+		values[i] = v.stringValue()
+	}
+	return strings.Join(names, ", ") + " = " + strings.Join(values, ", ")
+}
+
+func (as *Assignment) dataType() Type {
+	if len(as.values) == 1 {
+		return as.values[0].dataType()
+	}
+	tt := &TupleType{elemTypes: make([]Type, len(as.values))}
+	for i, v := range as.values {
+		tt.elemTypes[i] = v.dataType()
+	}
+	return tt
+}
+
+// valueCount returns the number of values assigned to the variables: the number of expressions or,
+// for a single tuple, its size. A single value of unknown type can be assigned to any number of variables.
+func valueCount(variables []*Variable, values []expression) int {
+	if len(values) != 1 {
+		return len(values)
+	}
+	switch t := values[0].dataType().(type) {
+	case *TupleType:
+		return len(t.elemTypes)
+	case *TBD:
+		return len(variables)
+	}
+	return 1
+}
+
+func (pe *ParenthesisExp) String() string {
+	return prettyPrint(pe, 0)
+}
+
+func (pe *ParenthesisExp) stringValue() string {
+	values := make([]string, len(pe.expressions))
+	for i, e := range pe.expressions {
+		values[i] = e.stringValue()
+	}
+	return "(" + strings.Join(values, ", ") + ")"
+}
+
+// dataType is the type of the expression for a single expression between parenthesis and a
+// TupleType for more.
+func (pe *ParenthesisExp) dataType() Type {
+	switch len(pe.expressions) {
+	case 0:
+		return &TupleType{elemTypes: []Type{}}
+	case 1:
+		return pe.expressions[0].dataType()
+	}
+	tt := &TupleType{elemTypes: make([]Type, len(pe.expressions))}
+	for i, e := range pe.expressions {
+		tt.elemTypes[i] = e.dataType()
+	}
+	return tt
+}
+
+func (vd *VariableDeclaration) String() string {
+	return prettyPrint(vd, 0)
+}
+
+func (vd *VariableDeclaration) value() string {
+	declarations := make([]string, len(vd.variables))
+	for i, v := range vd.variables {
+		declarations[i] = v.name + " " + v.varType.String()
+	}
+	if len(vd.values) == 0 {
+		return strings.Join(declarations, ", ")
+	}
+	values := make([]string, len(vd.values))
+	for i, v := range vd.values {
+		values[i] = v.stringValue()
+	}
+	return strings.Join(declarations, ", ") + " = " + strings.Join(values, ", ")
+}
+
+func (td *TypeDeclaration) String() string {
+	return prettyPrint(td, 0)
+}
+
+func (td *TypeDeclaration) value() string {
+	bt := td.variable.varType.(*BocType)
+	signature := *bt
+	signature.name = ""
+	if td.body == nil {
+		return td.variable.name + " " + signature.String()
+	}
+	return td.variable.name + " " + signature.String() + " = {}"
+}
+
+func (vd *VariantDeclaration) String() string {
+	return prettyPrint(vd, 0)
+}
+
+func (vd *VariantDeclaration) value() string {
+	cases := make([]string, len(vd.cases))
+	for i, c := range vd.cases {
+		signature := *c.varType.(*BocType)
+		signature.name = ""
+		cases[i] = c.name + strings.TrimPrefix(signature.String(), "#")
+	}
+	return vd.variable.name + " { " + strings.Join(cases, ", ") + " }"
+}
+
+func (w *When) String() string {
+	return prettyPrint(w, 0)
+}
+
+func (w *When) stringValue() string {
+	cases := make([]string, len(w.cases))
+	for i, c := range w.cases {
+		condition := "_"
+		if c.condition != nil {
+			condition = c.condition.stringValue()
+		}
+		cases[i] = "{ " + condition + " => " + c.body.stringValue() + " }"
+	}
+	return "when " + strings.Join(cases, ", ")
+}
+
+func (w *When) dataType() Type {
+	return w.whenType
+}
+
+func (i *Index) String() string {
+	return prettyPrint(i, 0)
+}
+
+func (i *Index) stringValue() string {
+	return i.receiver.stringValue() + "[" + i.index.stringValue() + "]"
+}
+
+func (i *Index) dataType() Type {
+	return i.indexType
+}
+
+func (ia *IndexAssignment) String() string {
+	return prettyPrint(ia, 0)
+}
+
+func (ia *IndexAssignment) stringValue() string {
+	return ia.target.stringValue() + " = " + ia.value.stringValue()
+}
+
+func (ia *IndexAssignment) dataType() Type {
+	return ia.value.dataType()
+}
+
+func (m *Match) String() string {
+	return prettyPrint(m, 0)
+}
+
+func (m *Match) stringValue() string {
+	cases := make([]string, len(m.cases))
+	for i, c := range m.cases {
+		pattern := "_"
+		if c.pattern != nil {
+			pattern = c.pattern.name
+		}
+		cases[i] = "{ " + pattern + " => " + c.body.stringValue() + " }"
+	}
+	return m.value.stringValue() + " match " + strings.Join(cases, ", ")
+}
+
+func (m *Match) dataType() Type {
+	return m.matchType
+}
+
+func (b *BadExpr) String() string {
+	return prettyPrint(b, 0)
+}
+
+func (b *BadExpr) stringValue() string {
+	return "<bad expression>"
+}
+
+func (b *BadExpr) dataType() Type {
+	return newTBD()
+}
+
+func (r *Return) String() string {
+	return prettyPrint(r, 0)
+}
+
+func (r *Return) value() string {
+	values := make([]string, len(r.values))
+	for i, v := range r.values {
+		values[i] = v.stringValue()
+	}
+	return strings.TrimSpace("return " + strings.Join(values, ", "))
+}
+
+// resultType returns the type of the values of the return: the type of its value, a tuple if it
+// has several or nil if it has none.
+func (r *Return) resultType() Type {
+	switch len(r.values) {
+	case 0:
+		return nil
+	case 1:
+		return r.values[0].dataType()
+	}
+	types := make([]Type, len(r.values))
+	for i, v := range r.values {
+		types[i] = v.dataType()
+	}
+	return &TupleType{elemTypes: types}
+}
+
+func (b *Break) String() string {
+	return prettyPrint(b, 0)
+}
+
+func (b *Break) value() string {
+	return "break"
+}
+
+func (c *Continue) String() string {
+	return prettyPrint(c, 0)
+}
+
+func (c *Continue) value() string {
+	return "continue"
+}
+
+func (b *BadStmt) String() string {
+	return prettyPrint(b, 0)
+}
+
+func (b *BadStmt) value() string {
+	return "<bad statement>"
+}
+
 func (v *Variable) String() string {
 	return prettyPrint(v, 0)
 }
@@ -95,8 +681,30 @@ func (boc *Boc) stringValue() string {
 	return "{}"
 }
 
+// dataType is a BocType with the variables declared in the block and the type of its last
+// expression as the result.
 func (boc *Boc) dataType() Type {
-	return newBocType()
+	bt := newBocType()
+	for _, exp := range boc.expressions {
+		if sd, ok := exp.(*ShortDeclaration); ok {
+			bt.variables = append(bt.variables, sd.variable)
+		}
+	}
+	for _, stmt := range boc.statements {
+		if vd, ok := stmt.(*VariableDeclaration); ok {
+			bt.variables = append(bt.variables, vd.variables...)
+		}
+	}
+	if len(boc.expressions) > 0 {
+		bt.result = boc.expressions[len(boc.expressions)-1].dataType()
+	}
+	// a boc that ends with a return has the type of its values
+	if nodes := boc.nodes(); len(nodes) > 0 {
+		if r, ok := nodes[len(nodes)-1].(*Return); ok && len(r.values) > 0 {
+			bt.result = r.resultType()
+		}
+	}
+	return bt
 }
 
 func (bl *BasicLit) String() string {
@@ -111,6 +719,25 @@ func (bl *BasicLit) dataType() Type {
 	return bl.basicType
 }
 
+func (is *InterpolatedString) String() string {
+	return prettyPrint(is, 0)
+}
+
+func (is *InterpolatedString) stringValue() string {
+	var sb strings.Builder
+	for i, part := range is.parts {
+		sb.WriteString(strings.ReplaceAll(part, "`", "\\`"))
+		if i < len(is.values) {
+			sb.WriteString("`" + is.values[i].stringValue() + "`")
+		}
+	}
+	return sb.String()
+}
+
+func (is *InterpolatedString) dataType() Type {
+	return new(StringType)
+}
+
 func (al *ArrayLit) String() string {
 	return prettyPrint(al, 0)
 }
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -23,10 +24,39 @@ func NewSourceFile(root, path, absolutePath string) SourceFile {
 var logger = log.Default()
 
 const (
-	target_dir = "target/"
+	DefaultTargetDir    = "target/"
+	DefaultGeneratedDir = "generated"
 )
 
-func Build(input []SourceFile, keepGeneratedSource bool) {
+// BuildOptions controls where Build places the generated Go sources and the compiled binaries.
+type BuildOptions struct {
+	// TargetDir is the directory where the compiled binaries are written.
+	TargetDir string
+	// GeneratedDir is the directory where the generated Go sources are kept when KeepGeneratedSource is true.
+	GeneratedDir string
+	// KeepGeneratedSource keeps the generated Go sources after the build, otherwise they are removed.
+	KeepGeneratedSource bool
+}
+
+// DefaultBuildOptions returns the options used when none are given in the command line.
+func DefaultBuildOptions() BuildOptions {
+	return BuildOptions{
+		TargetDir:           DefaultTargetDir,
+		GeneratedDir:        DefaultGeneratedDir,
+		KeepGeneratedSource: true,
+	}
+}
+
+// BuildResult is the outcome of a Build: the binaries produced and every problem found
+// in any of the source files and compilation phases.
+type BuildResult struct {
+	Binaries    []string
+	Diagnostics *Diagnostics
+}
+
+// Build compiles every source file in input into a binary under options.TargetDir.
+// A file with errors is reported in the result's Diagnostics and skipped, the rest of the files are still compiled.
+func Build(input []SourceFile, options BuildOptions) *BuildResult {
 	// read source file
 	// tokenize
 	// create ast
@@ -35,64 +65,126 @@ func Build(input []SourceFile, keepGeneratedSource bool) {
 	// IR
 	// generate code
 	// compile the code
+	// the spans of the diagnostics are resolved with the files of this build
+	files := &fileSet{}
+	result := &BuildResult{Diagnostics: &Diagnostics{sources: files}}
 
-	tmpDir, cleanup := createTempDir(keepGeneratedSource)
+	tmpDir, cleanup, e := createTempDir(options.GeneratedDir, options.KeepGeneratedSource)
+	if e != nil {
+		result.Diagnostics.addError("", codeCodegen, e)
+		return result
+	}
 	defer cleanup()
 
 	for _, sourceFile := range input {
-		fmt.Println()
+		logger.Println()
 		logger.Printf("Processing: %s\n", sourceFile.AbsolutePath)
-		content, e := os.ReadFile(sourceFile.AbsolutePath)
-
+		boc := frontEnd(files, sourceFile, result.Diagnostics)
+		if boc == nil {
+			continue
+		}
 		parts := strings.Split(sourceFile.Path, "/")
 		leaf := strings.Split(parts[len(parts)-1], ".")[0]
-		tokens, e := Tokenize(parts, string(content))
-		boc, e := Parse(parts, tokens)
-		if e != nil {
-			logger.Fatal(e)
-		}
 		// ir
 		logger.Printf("IR: %v\n", boc)
 
 		// generate code
-		fileName, e := GenerateCode(tmpDir, boc, leaf+".go")
-
+		fileName, e := GenerateCode(tmpDir, files, boc, leaf+".go")
 		if e != nil {
-			log.Fatalf("%q", e)
-			return
+			result.Diagnostics.addError(sourceFile.Path, codeCodegen, e)
+			continue
 		}
 		logger.Printf("go build %s\n", fileName)
 		// compile the code
-		gobuild(leaf, fileName)
+		binary, e := gobuild(options.TargetDir, leaf, fileName)
+		if e != nil {
+			result.Diagnostics.addError(sourceFile.Path, codeGoBuild, e)
+			continue
+		}
+		result.Binaries = append(result.Binaries, binary)
+	}
+	return result
+}
 
+// Check runs the front end (tokenizer, parser and semantic passes) over every source file
+// without generating or compiling any code.
+// It returns the diagnostics of all the files.
+func Check(input []SourceFile) *Diagnostics {
+	files := &fileSet{}
+	diagnostics := &Diagnostics{sources: files}
+	for _, sourceFile := range input {
+		logger.Printf("Checking: %s\n", sourceFile.AbsolutePath)
+		frontEnd(files, sourceFile, diagnostics)
 	}
+	return diagnostics
 }
 
-func gobuild(name, fileName string) {
-	_ = os.MkdirAll(target_dir, 0750)
-	outputFile := fmt.Sprintf("%s%s", target_dir, name)
-	//logger.Printf("Generated %s", outputFile)
+// frontEnd reads, tokenizes, parses and checks a source file and returns its AST. The file is added
+// to files. The problems found are added to diagnostics, if there is any error the returned AST is nil.
+func frontEnd(files *fileSet, sourceFile SourceFile, diagnostics *Diagnostics) *Boc {
+	content, e := os.ReadFile(sourceFile.AbsolutePath)
+	if e != nil {
+		diagnostics.addError(sourceFile.Path, codeRead, e)
+		return nil
+	}
+	parts := strings.Split(sourceFile.Path, "/")
+	// lexical errors don't stop the parser, so all the problems of the file are reported at once
+	tokens, lexicalError := Tokenize(files, parts, string(content))
+	if lexicalError != nil {
+		diagnostics.addError(sourceFile.Path, codeLexical, lexicalError)
+	}
+	boc, e := Parse(files, parts, tokens)
+	if e != nil {
+		diagnostics.addError(sourceFile.Path, codeSyntax, e)
+		return nil
+	}
+	if lexicalError != nil {
+		return nil
+	}
+	// the warnings of the checker don't stop the compilation
+	if checked := check(files, boc); checked != nil {
+		diagnostics.addError(sourceFile.Path, codeSemantic, checked)
+		if checked.HasErrors() {
+			return nil
+		}
+	}
+	return boc
+}
+
+// gobuild compiles fileName into a binary called name under targetDir and returns the binary path.
+// If go build fails the error contains its output.
+func gobuild(targetDir, name, fileName string) (string, error) {
+	if e := os.MkdirAll(targetDir, 0750); e != nil {
+		return "", e
+	}
+	outputFile := filepath.Join(targetDir, name)
 
 	cmd := exec.Command("go", "build", "-o", outputFile, fileName)
-	output, _ := cmd.CombinedOutput()
+	output, e := cmd.CombinedOutput()
+	if e != nil {
+		return "", fmt.Errorf("go build %s failed: %v\n%s", fileName, e, output)
+	}
 	if len(output) > 0 {
 		logger.Println(string(output))
 	}
+	return outputFile, nil
 }
 
 // createTempDir creates a temporary directory for generated source files.
-// It returns the path to the directory and a cleanup function that removes the directory.
-// If keepGeneratedSource is true, the source is created under the ./generated and the cleanup function is a no-op.
-func createTempDir(keepGeneratedSource bool) (string, func()) {
-	generatedDir := ""
+// It returns the path to the directory and a cleanup function that removes the directory, or an error if it can't be created.
+// If keepGeneratedSource is true, the source is created under generatedDir and the cleanup function is a no-op,
+// otherwise it is created under the system temporary directory.
+func createTempDir(generatedDir string, keepGeneratedSource bool) (string, func(), error) {
+	parentDir := ""
 	if keepGeneratedSource {
-		generatedDir = "generated"
-		_ = os.MkdirAll(generatedDir, 0750)
+		parentDir = generatedDir
+		if e := os.MkdirAll(parentDir, 0750); e != nil {
+			return "", nil, e
+		}
 	}
-	tmpDir, e := os.MkdirTemp(generatedDir, "yzc_generated_go")
-
+	tmpDir, e := os.MkdirTemp(parentDir, "yzc_generated_go")
 	if e != nil {
-		logger.Fatalf("%q", e)
+		return "", nil, e
 	}
 
 	cleanup := func() {
@@ -103,5 +195,5 @@ func createTempDir(keepGeneratedSource bool) (string, func()) {
 		}
 	}
 
-	return tmpDir, cleanup
+	return tmpDir, cleanup, nil
 }
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuild_Run(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not available")
+	}
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "Write to a declared dictionary",
+			source: "c [String:Int]\nc[\"k\": 1]\nc[\"j\"] = 2\nprint(c)",
+			want:   "map[j:2 k:1]\n",
+		},
+		{
+			name:   "Tuples",
+			source: "swap #(a Int, b Int, Int, Int) = { (b, a) }\nx Int, y Int = swap(1, 2)\nx, y = y, x\npair: { (3, \"three\") }\nn Int\ns String\nn, s = pair()\nprint(x, y, n, s)\nprint(swap(x, y))",
+			want:   "1 2 3 three\n2 1\n",
+		},
+		{
+			name:   "Assignment used as a value",
+			source: "a: 1\nb: a = 2\nd: [1]\nprint(a, b, d[0] = 3, d)",
+			want:   "2 2 3 [3]\n",
+		},
+		{
+			name:   "Break and continue in a when used as a value",
+			source: "i: 0\ntotal: 0\nwhile({ i < 10 }, {\n    i = i + 1\n    x: when { i == 2 => continue }, { i > 5 => break }, { _ => i * 10 }\n    total = total + x\n})\nprint(i, total)",
+			want:   "6 130\n",
+		},
+		{
+			name:   "Names of the packages and helpers of the generated code",
+			source: "fmt: 3\nos: 4\nyzElement: [5]\nresult: { r: 6; r }\nprint(fmt, os, yzElement[0], result())",
+			want:   "3 4 5 6\n",
+		},
+		{
+			name:   "Interpolation of a variable named like a package",
+			source: "strconv: 3\nprint(\"n `strconv`\")",
+			want:   "n 3\n",
+		},
+		{
+			name:   "Invocation of a block signature without a block",
+			source: "f #(x Int, Int)\nprint(f(1))",
+			want:   "0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.yz")
+			if err := os.WriteFile(path, []byte(tt.source), 0600); err != nil {
+				t.Fatal(err)
+			}
+			options := BuildOptions{TargetDir: filepath.Join(dir, "target"), GeneratedDir: dir}
+			result := Build([]SourceFile{NewSourceFile(dir, "main.yz", path)}, options)
+			if result.Diagnostics.HasErrors() || len(result.Binaries) != 1 {
+				t.Fatalf("Build() = %v, %v", result.Binaries, result.Diagnostics)
+			}
+			got, err := exec.Command(result.Binaries[0]).CombinedOutput()
+			if err != nil {
+				t.Fatalf("running the program: %v\n%s", err, got)
+			}
+			if string(got) != tt.want {
+				t.Errorf("the program wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checker walks the AST after it is parsed and reports the problems that need a whole construct
+// to be found, like a match that doesn't cover every case of a variant or a break outside a loop.
+type checker struct {
+	diagnostics Diagnostics
+	inLoop      bool   // the nodes are in the body of a loop, with no boc literal in between
+	results     []Type // the result types of the bocs being checked, the innermost is the last one
+	value       bool   // the value of the node being checked is used, it's not a statement of a block
+}
+
+// check runs the semantic checks over the boc of a file and returns the errors and warnings found,
+// or nil if there are none. files has the file of the boc.
+func check(files *fileSet, boc *Boc) *Diagnostics {
+	// the file boc has no result to return
+	c := &checker{diagnostics: Diagnostics{sources: files}, results: []Type{nil}}
+	c.block(fileBoc(boc), false)
+	if len(c.diagnostics.list) > 0 {
+		return &c.diagnostics
+	}
+	return nil
+}
+
+// node checks a node of the AST and the nodes it contains.
+func (c *checker) node(node interface{}) {
+	// the nodes inside another one are values, the blocks set it for their statements
+	value := c.value
+	c.value = true
+	switch n := node.(type) {
+	case *Boc:
+		// a boc literal is not part of the loop it's in, a break in it doesn't end the loop
+		inLoop := c.inLoop
+		c.inLoop = false
+		c.results = append(c.results, n.dataType().(*BocType).result)
+		c.block(n, true)
+		c.results = c.results[:len(c.results)-1]
+		c.inLoop = inLoop
+	case *ArrayLit:
+		c.nodes(n.expressions)
+	case *InterpolatedString:
+		c.nodes(n.values)
+	case *DictLit:
+		c.nodes(n.keys)
+		c.nodes(n.values)
+	case *ShortDeclaration:
+		c.node(n.value)
+	case *KeyValue:
+		c.node(n.key)
+		c.node(n.val)
+	case *Invocation:
+		if condition, body, ok := loopOf(n); ok {
+			c.node(condition)
+			inLoop := c.inLoop
+			c.inLoop = true
+			c.block(body, false)
+			c.inLoop = inLoop
+			return
+		}
+		c.node(n.callee)
+		c.nodes(n.args)
+		for _, a := range n.namedArgs {
+			c.node(a.value)
+		}
+		c.arguments(n)
+	case *TypeInstantiation:
+		for _, a := range n.values {
+			c.node(a.value)
+		}
+		c.typeArguments(n)
+	case *MemberAccess:
+		c.node(n.receiver)
+	case *Index:
+		c.node(n.receiver)
+		c.node(n.index)
+	case *IndexAssignment:
+		c.node(n.target)
+		c.node(n.value)
+	case *Assignment:
+		c.nodes(n.values)
+	case *ParenthesisExp:
+		c.nodes(n.expressions)
+	case *VariableDeclaration:
+		c.nodes(n.values)
+	case *TypeDeclaration:
+		if n.body != nil {
+			c.node(n.body)
+		}
+	case *When:
+		for _, wc := range n.cases {
+			if wc.condition != nil {
+				c.node(wc.condition)
+			}
+			c.block(wc.body, value)
+		}
+		if value {
+			c.whenValue(n)
+		}
+	case *Match:
+		c.node(n.value)
+		for _, mc := range n.cases {
+			c.block(mc.body, value)
+		}
+		c.match(n)
+	case *Return:
+		c.nodes(n.values)
+		c.returnValues(n)
+	case *Break, *Continue:
+		if !c.inLoop {
+			name := n.(statement).value()
+			c.diagnostics.Add(newDiagnostic(spanOf(n), codeSemantic, name+" is not inside a loop").
+				withHint("break and continue can only be used in the body of a loop, like while({ condition }, { ... })"))
+		}
+	}
+}
+
+// block checks the nodes of a block and reports the code after a return, break or continue, which is
+// never evaluated. The bodies of the cases of a when or a match are blocks of the boc they are in.
+// If value is true the last node is the value of the block.
+func (c *checker) block(b *Boc, value bool) {
+	var end statement
+	reported := false
+	nodes := b.nodes()
+	for i, n := range nodes {
+		if end != nil && !reported {
+			c.warning(newDiagnostic(spanOf(n), codeSemantic, "unreachable code after "+strings.Fields(end.value())[0]).
+				withLabel(spanOf(end), "the block ends here"))
+			reported = true
+		}
+		c.value = value && i == len(nodes)-1
+		c.node(n)
+		switch n.(type) {
+		case *Return, *Break, *Continue:
+			if end == nil {
+				end = n.(statement)
+			}
+		}
+	}
+}
+
+// returnValues reports a return whose values don't match the result type of the boc it ends.
+func (c *checker) returnValues(r *Return) {
+	result := c.results[len(c.results)-1]
+	if result == nil || isTBD(result) {
+		return
+	}
+	if len(r.values) == 0 {
+		c.diagnostics.Add(newDiagnostic(r.span, codeSemantic, "missing the value of the return, the block returns "+result.String()))
+		return
+	}
+	if t := r.resultType(); !assignable(result, t) {
+		c.diagnostics.Add(newDiagnostic(r.span, codeType, fmt.Sprintf("cannot return %s from a block that returns %s", t, result)))
+	}
+}
+
+// whenValue reports a when used as a value without a default case, it would have no value when
+// none of the conditions is true. A when whose cases have no value can be used as the last
+// expression of a block: { when { n > 0 => print(n) } }.
+func (c *checker) whenValue(w *When) {
+	if isTBD(w.whenType) {
+		return
+	}
+	for _, wc := range w.cases {
+		if wc.condition == nil {
+			return
+		}
+	}
+	c.diagnostics.Add(newDiagnostic(w.span, codeSemantic, "the when is used as a value but it has no default case").
+		withHint("add a default case { _ => ... } with the value when none of the conditions is true"))
+}
+
+func (c *checker) nodes(expressions []expression) {
+	for _, e := range expressions {
+		c.node(e)
+	}
+}
+
+// match reports the cases of a match that are never evaluated: those after the default case and
+// those whose type was already matched. When the value is a variant it also reports the types that
+// are not cases of it and, if there is no default case, the cases of the variant not matched.
+func (c *checker) match(m *Match) {
+	variant, _ := m.value.dataType().(*VariantType)
+	matched := map[string]*MatchCase{}
+	var defaultCase *MatchCase
+	for _, mc := range m.cases {
+		if defaultCase != nil {
+			c.warning(newDiagnostic(mc.span, codeSemantic, "unreachable case after the default case").
+				withLabel(defaultCase.span, "the default case"))
+			continue
+		}
+		if mc.pattern == nil {
+			defaultCase = mc
+			continue
+		}
+		name := mc.pattern.name
+		if previous, ok := matched[name]; ok {
+			c.warning(newDiagnostic(mc.span, codeSemantic, fmt.Sprintf("unreachable case, %s is already matched", name)).
+				withLabel(previous.span, name+" is matched here"))
+			continue
+		}
+		matched[name] = mc
+		if variant != nil && !isCaseOf(mc.pattern.varType, variant) {
+			c.diagnostics.Add(newDiagnostic(mc.pattern.span, codeSemantic,
+				fmt.Sprintf("%s is not a case of the variant %s", name, variant.name)).
+				withHint("the cases of " + variant.name + " are " + caseNames(variant)))
+		}
+	}
+	if variant == nil || defaultCase != nil {
+		return
+	}
+	var missing []string
+	for _, vc := range variant.cases {
+		if _, ok := matched[vc.name]; !ok {
+			missing = append(missing, vc.name)
+		}
+	}
+	if len(missing) > 0 {
+		c.diagnostics.Add(newDiagnostic(m.span, codeSemantic,
+			fmt.Sprintf("the match doesn't cover every case of %s, missing %s", variant.name, strings.Join(missing, ", "))).
+			withLabel(spanOf(m.value), "this value is "+variant.name).
+			withHint("add a case for each of them or a default case { _ => ... }"))
+	}
+}
+
+// arguments reports the arguments of an invocation that can't be assigned to the parameters of the
+// block invoked, passed in order or by name. The parameters of a generic block take the types inferred
+// from the arguments: id(1) passes an Int to id #(T, x T, T). It also reports too many arguments, the
+// named arguments that are not parameters or are passed twice and, for a block signature, the
+// parameters not passed. The variables of a block literal can have a value, they can be left out.
+func (c *checker) arguments(inv *Invocation) {
+	bt, ok := inv.callee.dataType().(*BocType)
+	if !ok || bt.name != "" {
+		return
+	}
+	if len(bt.typeParams) > 0 {
+		bt = inv.typeArgs(bt).substitute(bt).(*BocType)
+	}
+	name := inv.callee.stringValue()
+	params := signatureParams(bt)
+	check := func(param *Variable, arg expression) {
+		if t := arg.dataType(); !assignable(param.varType, t) {
+			d := newDiagnostic(spanOf(arg), codeType, fmt.Sprintf("cannot use %s value as %s in the argument %s of %s",
+				t, param.varType, param.name, name))
+			if param.span != (span{}) {
+				d.withLabel(param.span, "declared as "+param.varType.String()+" here")
+			}
+			c.diagnostics.Add(d)
+		}
+	}
+	passed := map[string]span{}
+	for i, a := range inv.args {
+		if i >= len(params) {
+			c.diagnostics.Add(newDiagnostic(spanOf(a), codeType, fmt.Sprintf("too many arguments for %s, it takes %d", name, len(params))))
+			break
+		}
+		check(params[i], a)
+		passed[params[i].name] = spanOf(a)
+	}
+	for _, na := range inv.namedArgs {
+		param := variableNamed(params, na.name)
+		switch {
+		case passed[na.name] != (span{}):
+			c.diagnostics.Add(newDiagnostic(na.span, codeType, "duplicate argument \""+na.name+"\"").
+				withLabel(passed[na.name], "first passed here"))
+		case param == nil:
+			c.diagnostics.Add(newDiagnostic(na.span, codeType, fmt.Sprintf("%s has no parameter \"%s\"", name, na.name)))
+		default:
+			check(param, na.value)
+			passed[na.name] = na.span
+		}
+	}
+	if !bt.signature {
+		return
+	}
+	var missing []string
+	for _, param := range params {
+		if _, ok := passed[param.name]; !ok {
+			missing = append(missing, param.name)
+		}
+	}
+	if len(missing) > 0 {
+		c.diagnostics.Add(newDiagnostic(inv.span, codeType, fmt.Sprintf("missing the argument %s of %s", strings.Join(missing, ", "), name)).
+			withHint("pass a value for each parameter of the block signature"))
+	}
+}
+
+// variableNamed returns the variable with the name, or nil if there is none.
+func variableNamed(variables []*Variable, name string) *Variable {
+	for _, v := range variables {
+		if v.name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// typeArguments reports the instantiation of a generic type whose type arguments can't be inferred
+// from the values of its members nor from the variable it's assigned to.
+func (c *checker) typeArguments(ti *TypeInstantiation) {
+	name, args := ti.instType.name, ti.instType.typeArgs
+	if vt, ok := ti.dataType().(*VariantType); ok {
+		name, args = vt.name, vt.typeArgs
+	}
+	params := typeParamsOf(ti.typeName.varType.(*BocType))
+	var missing []string
+	for i, t := range args {
+		if isTBD(t) {
+			missing = append(missing, params[i].name)
+		}
+	}
+	if len(missing) > 0 {
+		c.diagnostics.Add(newDiagnostic(ti.span, codeType,
+			fmt.Sprintf("cannot infer the type of %s in %s", strings.Join(missing, ", "), ti.stringValue())).
+			withHint(fmt.Sprintf("declare the type of the variable, like a %s = %s", instanceExample(name, len(args)), ti.stringValue())))
+	}
+}
+
+// warning adds a diagnostic that doesn't stop the compilation.
+func (c *checker) warning(d *Diagnostic) {
+	d.Severity = SeverityWarning
+	c.diagnostics.Add(d)
+}
+
+// isCaseOf returns true if t is the type of one of the cases of the variant.
+func isCaseOf(t Type, variant *VariantType) bool {
+	for _, vc := range variant.cases {
+		if t == vc {
+			return true
+		}
+	}
+	return false
+}
+
+// caseNames returns the names of the cases of the variant separated by ",".
+func caseNames(variant *VariantType) string {
+	names := make([]string, len(variant.cases))
+	for i, vc := range variant.cases {
+		names[i] = vc.name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	option := "Option {\n    Some(value Int),\n    None()\n}\nopt Option = Some(1)\n"
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name:   "Every case is matched",
+			source: option + "a: opt match { Some => opt.value }, { None => 0 }",
+		},
+		{
+			name:   "Default case",
+			source: option + "a: opt match { None => 0 }, { _ => 1 }",
+		},
+		{
+			name:   "Missing cases",
+			source: option + "a: opt match { None => 0 }",
+			want: []string{
+				"error: [check: line: 6 col: 4] the match doesn't cover every case of Option, missing Some\nHint: add a case for each of them or a default case { _ => ... }",
+			},
+		},
+		{
+			name:   "Unreachable cases",
+			source: option + "a: opt match { Some => 1 }, { None => 2 }, { Some => 3 }\nb: opt match { _ => 1 }, { None => 2 }",
+			want: []string{
+				"warning: [check: line: 6 col: 44] unreachable case, Some is already matched",
+				"warning: [check: line: 7 col: 26] unreachable case after the default case",
+			},
+		},
+		{
+			name:   "Not a case of the variant",
+			source: option + "Point #(x Int, y Int)\na: opt match { Point => 1 }, { _ => 2 }",
+			want: []string{
+				"error: [check: line: 7 col: 16] Point is not a case of the variant Option\nHint: the cases of Option are Some, None",
+			},
+		},
+		{
+			name:   "Nested match",
+			source: option + "a: { opt match { Some => 1 } }\nb: 1 match { Int => 1 }",
+			want: []string{
+				"error: [check: line: 6 col: 6] the match doesn't cover every case of Option, missing None\nHint: add a case for each of them or a default case { _ => ... }",
+			},
+		},
+		{
+			name:   "Dictionary read",
+			source: "d: [\"one\": 1]\na: d[\"one\"] match { Some => 1 }\nb: d[\"two\"] match { Some => 1 }, { None => 0 }",
+			want: []string{
+				"error: [check: line: 2 col: 4] the match doesn't cover every case of Option, missing None\nHint: add a case for each of them or a default case { _ => ... }",
+			},
+		},
+		{
+			name:   "Break and continue in a loop",
+			source: "i: 0\nwhile({ i < 10 }, { i = i + 1; when { i == 2 => continue }, { i > 5 => break } })",
+		},
+		{
+			name:   "Break and continue outside a loop",
+			source: "when { 1 > 0 => break }\nwhile({ true }, { f: { continue } })",
+			want: []string{
+				"error: [check: line: 1 col: 17] break is not inside a loop\nHint: break and continue can only be used in the body of a loop, like while({ condition }, { ... })",
+				"error: [check: line: 2 col: 24] continue is not inside a loop\nHint: break and continue can only be used in the body of a loop, like while({ condition }, { ... })",
+			},
+		},
+		{
+			name:   "Unreachable code",
+			source: "a: { return 1\n2\n3 }\nwhile({ true }, { break; a = a })",
+			want: []string{
+				"warning: [check: line: 2 col: 1] unreachable code after return",
+				"warning: [check: line: 4 col: 26] unreachable code after break",
+			},
+		},
+		{
+			name:   "Return values",
+			source: "a: { when { 1 > 0 => return \"one\" }; 1 }\nb: { when { 1 > 0 => return }; 1 }\nc: { return 1 }",
+			want: []string{
+				"error: [check: line: 1 col: 22] cannot return String from a block that returns Int",
+				"error: [check: line: 2 col: 22] missing the value of the return, the block returns Int",
+			},
+		},
+		{
+			name:   "Type arguments",
+			source: "Option {\n    Some(value T),\n    None()\n}\na: Some(1)\nb Option(String) = None()\nc: None()",
+			want: []string{
+				"error: [check: line: 7 col: 4] cannot infer the type of T in None()\nHint: declare the type of the variable, like a Option(Int) = None()",
+			},
+		},
+		{
+			name:   "Argument count and names",
+			source: "add #(a Int, b Int, Int) = { a + b }\nw: add(1, 2, 3)\nx: add(1)\ny: add(a: 1, c: 2)\nz: add(a: 1, b: 2, a: 3)\nf: { n Int; n * 2 }\ng: f(1, 2)\nh: f(n: 1)",
+			want: []string{
+				"error: [check: line: 2 col: 14] too many arguments for add, it takes 2",
+				"error: [check: line: 3 col: 4] missing the argument b of add\nHint: pass a value for each parameter of the block signature",
+				"error: [check: line: 4 col: 14] add has no parameter \"c\"",
+				"error: [check: line: 4 col: 4] missing the argument b of add\nHint: pass a value for each parameter of the block signature",
+				"error: [check: line: 5 col: 20] duplicate argument \"a\"",
+				"error: [check: line: 7 col: 9] too many arguments for f, it takes 1",
+			},
+		},
+		{
+			name:   "When used as a value",
+			source: "n: 1\na: when { n > 0 => 1 }\nb: when { n > 0 => 1 }, { _ => 0 }\nc: { when { n > 0 => \"pos\" } }\nwhen { n > 0 => 1 }\nd: { when { n > 0 => print(n) } }\ne: n match { Int => when { n > 1 => 2 } }",
+			want: []string{
+				"error: [check: line: 2 col: 4] the when is used as a value but it has no default case\nHint: add a default case { _ => ... } with the value when none of the conditions is true",
+				"error: [check: line: 4 col: 6] the when is used as a value but it has no default case\nHint: add a default case { _ => ... } with the value when none of the conditions is true",
+				"error: [check: line: 7 col: 21] the when is used as a value but it has no default case\nHint: add a default case { _ => ... } with the value when none of the conditions is true",
+			},
+		},
+		{
+			name:   "Argument types",
+			source: "f #(n Int, Int) = { n }\na: f(\"x\")\nb: f(n: 1.5)\nid #(T, x T, T) = { x }\nc Int = id(1)\nd: f(id(2))\ne: 1 + \"s\"",
+			want: []string{
+				"error: [check: line: 2 col: 6] cannot use String value as Int in the argument n of f",
+				"error: [check: line: 3 col: 9] cannot use Decimal value as Int in the argument n of f",
+				"error: [check: line: 7 col: 8] cannot use String value as Int in the argument other of 1.+",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := &fileSet{}
+			tokens, err := Tokenize(files, []string{"check"}, tt.source)
+			if err != nil {
+				t.Errorf("Tokenize() error = \"%v\"", err)
+				return
+			}
+			boc, err := Parse(files, []string{"check"}, tokens)
+			if err != nil {
+				t.Errorf("Parse() error = \"%v\"", err)
+				return
+			}
+			var got []string
+			if ds := check(files, boc); ds != nil {
+				for _, d := range ds.All() {
+					got = append(got, d.Severity.String()+": "+d.Error())
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("check() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("check()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -1,41 +1,1115 @@
 package internal
 
 import (
+	"fmt"
+	"go/format"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
-	"text/template"
+	"unicode"
 )
 
-func GenerateCode(tempDir string, boc *Boc, bocGoName string) (string, error) {
-	content, e := Bytes(boc, bocGoName)
+func GenerateCode(tempDir string, files *fileSet, boc *Boc, bocGoName string) (string, error) {
+	content, e := Bytes(files, boc, bocGoName)
 	if e != nil {
-		logger.Fatalf("generate code error: %q", e)
-		return "", e
-
+		return "", fmt.Errorf("generate code error: %w", e)
 	}
 	fileName := filepath.Join(tempDir, bocGoName)
 	if err := os.WriteFile(fileName, content, 0750); err != nil {
-		logger.Fatalf("write error: %q", err)
-		return "", err
+		return "", fmt.Errorf("write error: %w", err)
 	}
 	return fileName, nil
 }
 
-func Bytes(boc *Boc, name string) ([]byte, error) {
-	goSourceTemplate, err := template.New("main").Parse(
-		`package main
+// Bytes returns the Go source of a main package that runs the boc of a source file. The user-defined
+// types become package level Go types and the expressions and statements of the file the body of main.
+// The constructs that can't be lowered to Go yet are reported as a *Diagnostics error. files has the
+// file of the boc, the positions in the runtime errors are taken from it.
+func Bytes(files *fileSet, boc *Boc, name string) ([]byte, error) {
+	g := &generator{types: &strings.Builder{}, out: &strings.Builder{}, files: files, diagnostics: Diagnostics{sources: files},
+		helpers: map[string]bool{}, imports: map[string]bool{},
+		generics: map[*BocType]*Boc{}}
+	g.block(fileBoc(boc), false)
+	if len(g.diagnostics.list) > 0 {
+		return nil, &g.diagnostics
+	}
+	var imports []string
+	for i := range g.imports {
+		imports = append(imports, i)
+	}
+	var helpers strings.Builder
+	for _, h := range goHelpers {
+		if g.helpers[h.name] {
+			helpers.WriteString(h.code + "\n\n")
+			imports = append(imports, h.imports...)
+		}
+	}
+	importDecl := ""
+	if len(imports) > 0 {
+		slices.Sort(imports)
+		for _, i := range slices.Compact(imports) {
+			importDecl += strconv.Quote(i) + "\n"
+		}
+		importDecl = "import (\n" + importDecl + ")\n\n"
+	}
+	source := "// Code generated by yzc as " + name + ". DO NOT EDIT.\n\npackage main\n\n" + importDecl +
+		g.types.String() + helpers.String() + "func main() {\n" + g.out.String() + "}\n"
+	formatted, e := format.Source([]byte(source))
+	if e != nil {
+		return nil, fmt.Errorf("invalid generated source: %v\n%s", e, source)
+	}
+	return formatted, nil
+}
+
+// generator lowers the AST of a source file to Go. Blocks become Go closures, the variables their
+// local variables and the invocations of Go operators like + or == become Go binary expressions.
+type generator struct {
+	types       *strings.Builder // declarations of the user-defined types
+	out         *strings.Builder // body of the function being generated
+	files       *fileSet         // the source files, for the positions of the runtime errors
+	diagnostics Diagnostics
+	inClosure   bool              // the function being generated is a closure, not main
+	loops       []*goLoop         // the loops being generated, the innermost is the last one
+	labels      int               // number of loop labels created, used to name them
+	valueCases  int               // depth of the when and match being generated as function literals
+	earlyReturn *goReturn         // the return from the cases of the outermost when or match used as a value
+	returns     int               // number of goReturns created, used to name their variables
+	branches    int               // number of goBranches created, used to name their variables
+	checks      []string          // checks of the early returns, written after the statement being generated
+	helpers     map[string]bool   // names of the goHelpers used by the generated code
+	imports     map[string]bool   // packages used by the generated code, besides the ones of the helpers
+	generics    map[*BocType]*Boc // the bodies of the generic blocks, generated at each invocation
+}
+
+// goHelper is a function or a type added to the generated code when it's used, like the bounds check
+// of the arrays, with the packages it imports.
+type goHelper struct {
+	name    string
+	imports []string
+	code    string
+}
+
+// goHelpers are written in this order after the user-defined types.
+var goHelpers = []goHelper{
+	{name: "option", code: `// yz_Option is the Option of a dictionary read, its values are one of the yz_Option_ structs.
+type yz_Option[T any] interface {
+	isOption()
+}
+
+type yz_Option_Some[T any] struct {
+	value T
+}
+
+func (yz_Option_Some[T]) isOption() {}
+
+type yz_Option_None[T any] struct {
+}
+
+func (yz_Option_None[T]) isOption() {}`},
+	{name: "element", imports: []string{"fmt", "os"}, code: `// yzElement returns the element i of a. If i is out of range it exits with the position of the index in the Yz source.
+func yzElement[T any](a []T, i int, position string) *T {
+	if i < 0 || i >= len(a) {
+		fmt.Fprintf(os.Stderr, "%s: index %d out of range [0:%d]\n", position, i, len(a))
+		os.Exit(2)
+	}
+	return &a[i]
+}`},
+	{name: "lookup", code: `// yzLookup returns the value of the key in d as an Option, None if d doesn't have the key.
+func yzLookup[K comparable, V any](d map[K]V, key K) yz_Option[V] {
+	if v, ok := d[key]; ok {
+		return yz_Option_Some[V]{value: v}
+	}
+	return yz_Option_None[V]{}
+}`},
+}
+
+// goLoop is a Go for loop with a label, used is true if a break or a continue refers to it.
+type goLoop struct {
+	label string
+	used  bool
+}
+
+// goReturn is a return from the cases of a when or a match used as a value. The cases are a function
+// literal, so the return sets the variables and ends it, and the function of the boc returns after
+// the literal is invoked.
+type goReturn struct {
+	returned  string // the variable set to true by the return
+	value     string // the variable with the value returned
+	valueType Type   // the type of the value returned, nil if the returns have no value
+	uses      int    // number of returns written
+	// the breaks and continues from the cases, they end the loops around the outermost when or match
+	branches   []*goBranch
+	branchUses int // number of breaks and continues written
+}
+
+// goBranch is a break or a continue from the cases of a when or a match used as a value. It sets
+// its variable to true and ends the function literal, the statement is written after it's invoked.
+type goBranch struct {
+	flag      string // the variable set to true by the break or the continue
+	statement string // the break or continue of the loop, with its label
+}
+
+// fileBoc returns the boc of the source file, without the blocks of its parent directories.
+func fileBoc(boc *Boc) *Boc {
+	for len(boc.expressions) == 1 && len(boc.statements) == 0 {
+		sd, ok := boc.expressions[0].(*ShortDeclaration)
+		if !ok || sd.span != (span{}) {
+			break
+		}
+		inner, ok := sd.value.(*Boc)
+		if !ok {
+			break
+		}
+		boc = inner
+	}
+	return boc
+}
+
+// unsupported reports a construct that can't be lowered to Go yet and returns a placeholder.
+func (g *generator) unsupported(node interface{}, what string) string {
+	g.diagnostics.Add(newDiagnostic(spanOf(node), codeCodegen, "code generation of "+what+" is not supported yet"))
+	return "nil"
+}
+
+// nodeName returns the name of the type of an AST node, like Assignment.
+func nodeName(node interface{}) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*internal.")
+}
+
+func (g *generator) line(format string, args ...interface{}) {
+	g.out.WriteString(fmt.Sprintf(format, args...) + "\n")
+}
+
+// block writes the expressions and statements of a boc. If result is true the last expression
+// is returned.
+func (g *generator) block(boc *Boc, result bool) {
+	nodes := boc.nodes()
+	for i, node := range nodes {
+		if exp, ok := node.(expression); ok && result && i == len(nodes)-1 {
+			g.returnValue(exp)
+			return
+		}
+		g.statement(node)
+		g.writeChecks()
+		switch node.(type) {
+		case *Return, *Break, *Continue:
+			// the rest of the block is never evaluated
+			return
+		}
+	}
+}
+
+// returnValue writes the return of the value of an expression, the variable of a declaration or an assignment.
+func (g *generator) returnValue(exp expression) {
+	switch e := exp.(type) {
+	case *ShortDeclaration:
+		g.statement(e)
+		g.writeChecks()
+		g.line("return %s", goName(e.variable.name))
+	case *Assignment:
+		g.statement(e)
+		g.writeChecks()
+		g.line("return %s", strings.Join(goNames(e.variables), ", "))
+	default:
+		value := g.values([]expression{exp})
+		if len(g.checks) > 0 {
+			// the value is returned after the early returns in it are checked
+			if _, ok := exp.dataType().(*TupleType); ok {
+				g.unsupported(exp, "returning a tuple from a when or a match that returns early")
+				return
+			}
+			g.line("yz_value := %s", value)
+			g.writeChecks()
+			value = "yz_value"
+		}
+		g.line("return %s", value)
+	}
+}
+
+// writeChecks writes the checks of the early returns of the statement just written.
+func (g *generator) writeChecks() {
+	for _, check := range g.checks {
+		g.out.WriteString(check)
+	}
+	g.checks = nil
+}
+
+// statement writes an expression or a statement of a block. Variables are followed by a blank
+// assignment, Go doesn't allow unused variables.
+func (g *generator) statement(node interface{}) {
+	switch n := node.(type) {
+	case *ShortDeclaration:
+		name := goName(n.variable.name)
+		if inner, ok := n.value.(*ShortDeclaration); ok {
+			// c: d: 1
+			g.statement(inner)
+			g.line("%s := %s", name, goName(inner.variable.name))
+		} else if _, ok := n.value.dataType().(*TupleType); ok {
+			// a Go variable holds one value, the results of a function are assigned to several
+			g.unsupported(n, "variables holding a tuple")
+			return
+		} else if vt, ok := n.variable.varType.(*VariantType); ok {
+			// o: Some(1) is an Option, it can hold the other cases later: o = None()
+			g.line("var %s %s = %s", name, goType(vt), g.expression(n.value))
+		} else {
+			g.line("%s := %s", name, g.expression(n.value))
+		}
+		g.line("_ = %s", name)
+	case *Assignment:
+		g.line("%s = %s", strings.Join(goNames(n.variables), ", "), g.values(n.values))
+	case *IndexAssignment:
+		g.indexAssignment(n, g.expression(n.value))
+	case *Invocation:
+		if _, _, ok := loopOf(n); ok {
+			g.loop(n)
+			return
+		}
+		if ma, ok := n.callee.(*MemberAccess); ok && goOperators[ma.member.name] {
+			// an operation is not a valid Go statement
+			g.line("_ = %s", g.expression(n))
+			return
+		}
+		g.line("%s", g.expression(n))
+	case *When:
+		g.when(n, false)
+	case *Match:
+		g.match(n, false)
+	case *Return:
+		g.returnStatement(n)
+	case *Break:
+		g.branch(n, "break")
+	case *Continue:
+		g.branch(n, "continue")
+	case expression:
+		g.line("_ = %s", g.expression(n))
+	case *VariableDeclaration:
+		if len(n.values) > 0 && len(n.values) != len(n.variables) {
+			// the variables take the values of a tuple: a Int, b String = pair()
+			names := goNames(n.variables)
+			for i, v := range n.variables {
+				g.line("var %s %s", names[i], goType(v.varType))
+			}
+			g.line("%s = %s", strings.Join(names, ", "), g.values(n.values))
+			for _, name := range names {
+				g.line("_ = %s", name)
+			}
+			return
+		}
+		for i, v := range n.variables {
+			if bt, ok := v.varType.(*BocType); ok && bt.name == "" && len(bt.typeParams) > 0 {
+				boc, ok := valueAt(n.values, i).(*Boc)
+				if !ok {
+					g.unsupported(v, "generic blocks without a body")
+					return
+				}
+				// a Go function literal can't have type parameters, each invocation gets its own
+				g.generics[bt] = boc
+				continue
+			}
+			name := goName(v.name)
+			varType := goType(v.varType)
+			bt, signature := v.varType.(*BocType)
+			signature = signature && bt.signature
+			if _, ok := v.varType.(*DictType); ok && len(n.values) == 0 {
+				// a nil Go map can't be written, the dictionary starts empty
+				g.line("%s := %s{}", name, varType)
+			} else if len(n.values) == 0 && signature {
+				// a nil Go function can't be invoked, the block returns the zero values of its results
+				g.line("var %s %s = %s", name, varType, zeroFunc(bt))
+			} else if len(n.values) == 0 {
+				g.line("var %s %s", name, varType)
+			} else if boc, ok := n.values[i].(*Boc); ok && signature {
+				g.line("var %s %s = %s", name, varType, g.closure(boc, bt, nil))
+			} else {
+				g.line("var %s %s = %s", name, varType, g.expression(n.values[i]))
+			}
+			g.line("_ = %s", name)
+		}
+	case *TypeDeclaration:
+		g.typeDeclaration(n)
+	case *VariantDeclaration:
+		g.variantDeclaration(n)
+	default:
+		g.unsupported(n, nodeName(n))
+	}
+}
+
+// typeDeclaration writes a struct with a field for each named member of the type.
+func (g *generator) typeDeclaration(td *TypeDeclaration) {
+	bt := td.variable.varType.(*BocType)
+	for _, v := range bt.variables {
+		if _, ok := v.varType.(*BocType); ok && td.body != nil {
+			g.unsupported(td, "the methods of a type")
+			return
+		}
+	}
+	g.types.WriteString(fmt.Sprintf("type %s%s struct {\n", goName(td.variable.name), goTypeParams(bt.typeParams)))
+	g.fields(bt)
+	g.types.WriteString("}\n\n")
+}
+
+// variantDeclaration writes an interface for the variant and a struct for each case that implements it.
+//
+//	Option { Some(value Int), None() }
+//
+// becomes
+//
+//	type Option interface{ isOption() }
+//	type Option_Some struct{ value int }
+//	func (Option_Some) isOption() {}
+//	type Option_None struct{}
+//	func (Option_None) isOption() {}
+//
+// The type parameters of a generic variant are type parameters of the interface and of every case.
+func (g *generator) variantDeclaration(vd *VariantDeclaration) {
+	vt := vd.variable.varType.(*VariantType)
+	name := goName(vd.variable.name)
+	marker := "is" + name
+	params := goTypeParams(vt.typeParams)
+	g.types.WriteString(fmt.Sprintf("// %s is a variant, its values are one of the %s_ structs.\ntype %s%s interface {\n%s()\n}\n\n",
+		name, name, name, params, marker))
+	for _, c := range vd.cases {
+		caseName := goName(vt.name) + "_" + goName(c.name)
+		g.types.WriteString(fmt.Sprintf("type %s%s struct {\n", caseName, params))
+		g.fields(c.varType.(*BocType))
+		g.types.WriteString("}\n\n")
+		g.types.WriteString(fmt.Sprintf("func (%s) %s() {}\n\n", variantCaseName(vt, c.name), marker))
+	}
+}
+
+// goTypeParams returns the Go type parameters of a generic type: [T any, U any].
+func goTypeParams(params []*GenericType) string {
+	if len(params) == 0 {
+		return ""
+	}
+	names := make([]string, len(params))
+	for i, tp := range params {
+		names[i] = goName(tp.name) + " any"
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// goTypeArgs returns the Go type arguments of an instance of a generic type, or its type parameters
+// in the declaration: [int] or [T].
+func goTypeArgs(params []*GenericType, args []Type) string {
+	if len(params) == 0 && len(args) == 0 {
+		return ""
+	}
+	types := typeArguments(params, args)
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = goType(t)
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+func (g *generator) fields(bt *BocType) {
+	for _, v := range bt.variables {
+		if v.name != "" {
+			g.types.WriteString(fmt.Sprintf("%s %s\n", goName(v.name), goType(v.varType)))
+		}
+	}
+}
+
+// expression returns the Go expression of a Yz expression.
+func (g *generator) expression(exp expression) string {
+	switch e := exp.(type) {
+	case *BasicLit:
+		if e.tt == STRING {
+			return strconv.Quote(e.val)
+		}
+		return e.val
+	case *Variable:
+		if bt, ok := e.varType.(*BocType); ok && g.generics[bt] != nil {
+			return g.unsupported(e, "generic blocks used as a value")
+		}
+		return goName(e.name)
+	case *InterpolatedString:
+		return g.interpolatedString(e)
+	case *ArrayLit:
+		elements := make([]string, len(e.expressions))
+		for i, element := range e.expressions {
+			elements[i] = g.expression(element)
+		}
+		return goType(e.arrayType) + "{" + strings.Join(elements, ", ") + "}"
+	case *DictLit:
+		entries := make([]string, len(e.keys))
+		for i := range e.keys {
+			entries[i] = g.expression(e.keys[i]) + ": " + g.expression(e.values[i])
+		}
+		return goType(e.dictType) + "{" + strings.Join(entries, ", ") + "}"
+	case *ParenthesisExp:
+		if len(e.expressions) != 1 {
+			return g.unsupported(e, "tuples")
+		}
+		return "(" + g.expression(e.expressions[0]) + ")"
+	case *Boc:
+		return g.closure(e, nil, nil)
+	case *Invocation:
+		return g.invocation(e)
+	case *TypeInstantiation:
+		return g.instantiation(e)
+	case *When:
+		if isTuple(e.whenType) {
+			return g.unsupported(e, "a when whose value is a tuple")
+		}
+		return g.caseValue(e.whenType, func() { g.when(e, true) })
+	case *Match:
+		if isTuple(e.matchType) {
+			return g.unsupported(e, "a match whose value is a tuple")
+		}
+		return g.caseValue(e.matchType, func() { g.match(e, true) })
+	case *Index:
+		return g.index(e)
+	case *Assignment, *IndexAssignment:
+		return g.assignmentValue(e)
+	case *MemberAccess:
+		// the fields of the structs of user-defined types and variant cases
+		if bt, ok := e.receiver.dataType().(*BocType); ok && bt.name != "" && hasVariable(bt, e.member.name) {
+			return g.expression(e.receiver) + "." + goName(e.member.name)
+		}
+		// the blocks are Go functions, their variables are not reachable from outside
+		return g.unsupported(e, "member access on values of type "+e.receiver.dataType().String())
+	}
+	return g.unsupported(exp, nodeName(exp))
+}
+
+// interpolatedString returns the concatenation of the literal text of a string and the values of its
+// placeholders converted to strings: "n is `n`" is "n is " + strconv.Itoa(n). The basic types are
+// converted with strconv, the other values are formatted with fmt.
+func (g *generator) interpolatedString(is *InterpolatedString) string {
+	var terms []string
+	for i, part := range is.parts {
+		if part != "" {
+			terms = append(terms, strconv.Quote(part))
+		}
+		if i < len(is.values) {
+			terms = append(terms, g.stringOf(is.values[i]))
+		}
+	}
+	if len(terms) == 0 {
+		return `""`
+	}
+	return strings.Join(terms, " + ")
+}
+
+// stringOf returns the Go expression that converts the value to a string.
+func (g *generator) stringOf(value expression) string {
+	v := g.expression(value)
+	switch value.dataType().(type) {
+	case *StringType:
+		return v
+	case *IntType:
+		g.imports["strconv"] = true
+		return "strconv.Itoa(" + v + ")"
+	case *DecimalType:
+		g.imports["strconv"] = true
+		return "strconv.FormatFloat(" + v + ", 'g', -1, 64)"
+	case *BoolType:
+		g.imports["strconv"] = true
+		return "strconv.FormatBool(" + v + ")"
+	}
+	g.imports["fmt"] = true
+	return "fmt.Sprint(" + v + ")"
+}
+
+// index returns the read of an element of an array or a dictionary. The index of an array is checked
+// at runtime, the read of a dictionary is an Option.
+func (g *generator) index(i *Index) string {
+	switch i.receiver.dataType().(type) {
+	case *ArrayType:
+		g.helpers["element"] = true
+		return fmt.Sprintf("*yzElement(%s, %s, %s)", g.expression(i.receiver), g.expression(i.index), g.yzPosition(spanOf(i.index)))
+	case *DictType:
+		g.helpers["option"], g.helpers["lookup"] = true, true
+		return fmt.Sprintf("yzLookup(%s, %s)", g.expression(i.receiver), g.expression(i.index))
+	}
+	return g.unsupported(i, "indexing a value of unknown type")
+}
+
+// indexAssignment writes the assignment of an element of an array or a dictionary.
+func (g *generator) indexAssignment(ia *IndexAssignment, value string) {
+	switch ia.target.receiver.dataType().(type) {
+	case *ArrayType:
+		g.line("%s = %s", g.index(ia.target), value)
+	case *DictType:
+		g.line("%s[%s] = %s", g.expression(ia.target.receiver), g.expression(ia.target.index), value)
+	default:
+		g.unsupported(ia, "indexing a value of unknown type")
+	}
+}
+
+// assignmentValue returns the function literal invoked to get the value of an assignment used as a
+// value, the value assigned: b: a = 2 is b := func() int { a = 2; return a }().
+func (g *generator) assignmentValue(exp expression) string {
+	outer, checks := g.out, g.checks
+	g.out, g.checks = &strings.Builder{}, nil
+	var value string
+	switch a := exp.(type) {
+	case *Assignment:
+		g.statement(a)
+		value = strings.Join(goNames(a.variables), ", ")
+	case *IndexAssignment:
+		// the element is not read again, a dictionary read is an Option
+		value = "yz_value"
+		g.line("yz_value := %s", g.expression(a.value))
+		g.indexAssignment(a, value)
+	}
+	if len(g.checks) > 0 {
+		// the checks would end the function literal instead of the function of the boc
+		g.unsupported(exp, "a return in the value of an assignment used as a value")
+	}
+	body := g.out.String()
+	g.out, g.checks = outer, checks
+	return "func() " + goType(exp.dataType()) + " {\n" + body + "return " + value + "\n}()"
+}
+
+// yzPosition returns the position of the span in the Yz source as a Go string literal: "main.yz:1:5".
+func (g *generator) yzPosition(s span) string {
+	p := g.files.position(s)
+	return strconv.Quote(fmt.Sprintf("%s:%d:%d", g.files.fileName(s), p.line, p.col))
+}
+
+// closure returns a Go function literal with the body of the boc. The function returns the value of
+// the last expression if its type is known. Otherwise, if the boc returns values early, the
+// function has a named result that is returned when the body ends without a return.
+// The boc of a block signature takes its parameters and returns its result type, the type parameters
+// of a generic one are aliases of their type arguments.
+func (g *generator) closure(boc *Boc, signature *BocType, typeArgs bindings) string {
+	outer, inClosure, loops, valueCases, earlyReturn, checks := g.out, g.inClosure, g.loops, g.valueCases, g.earlyReturn, g.checks
+	g.out, g.inClosure, g.loops, g.valueCases, g.earlyReturn, g.checks = &strings.Builder{}, true, nil, 0, nil, nil
+	defer func() {
+		g.out, g.inClosure, g.loops, g.valueCases, g.earlyReturn, g.checks = outer, inClosure, loops, valueCases, earlyReturn, checks
+	}()
+	params, result := "", ""
+	nodes := boc.nodes()
+	if signature != nil {
+		params = goParams(signature)
+		result = goResult(signature)
+		for _, tp := range signature.typeParams {
+			g.line("type %s = %s", goName(tp.name), goType(typeArgs[tp]))
+		}
+	} else if len(nodes) > 0 {
+		if last, ok := nodes[len(nodes)-1].(expression); ok && !isTBD(last.dataType()) {
+			result = goType(last.dataType()) + " "
+		}
+	}
+	if t := earlyReturnType(boc); result == "" && t != nil && !isTBD(t) {
+		g.block(boc, false)
+		if _, ok := nodes[len(nodes)-1].(*Return); !ok {
+			g.line("return")
+		}
+		return "func(" + params + ") (result " + goType(t) + ") {\n" + g.out.String() + "}"
+	}
+	g.block(boc, result != "")
+	return "func(" + params + ") " + result + "{\n" + g.out.String() + "}"
+}
+
+// zeroFunc returns the Go function literal of a block signature that returns the zero values of its
+// results: #(x Int, Int) is func(x int) (yz_result1 int) { return }.
+func zeroFunc(bt *BocType) string {
+	var types []Type
+	if tt, ok := bt.result.(*TupleType); ok {
+		types = tt.elemTypes
+	} else if bt.result != nil && !isTBD(bt.result) {
+		types = []Type{bt.result}
+	}
+	results := make([]string, len(types))
+	for i, t := range types {
+		results[i] = fmt.Sprintf("yz_result%d %s", i+1, goType(t))
+	}
+	if len(results) == 0 {
+		return "func(" + goParams(bt) + ") {\n}"
+	}
+	return "func(" + goParams(bt) + ") (" + strings.Join(results, ", ") + ") {\nreturn\n}"
+}
+
+// goParams returns the Go parameters of the named members of a block signature.
+func goParams(bt *BocType) string {
+	var params []string
+	for _, v := range signatureParams(bt) {
+		params = append(params, goName(v.name)+" "+goType(v.varType))
+	}
+	return strings.Join(params, ", ")
+}
+
+// goResult returns the Go result type of a block signature followed by a space, or "" if it has none.
+func goResult(bt *BocType) string {
+	if bt.result == nil || isTBD(bt.result) {
+		return ""
+	}
+	return goType(bt.result) + " "
+}
+
+// signatureParams returns the named members of a block signature, its parameters.
+func signatureParams(bt *BocType) []*Variable {
+	var params []*Variable
+	for _, v := range bt.variables {
+		if v.name != "" {
+			params = append(params, v)
+		}
+	}
+	return params
+}
+
+// earlyReturnType returns the type of the values of the first return of a boc that has them, or nil.
+// The returns in the cases of a when or a match and in the body of a loop end the boc too.
+func earlyReturnType(boc *Boc) Type {
+	for _, node := range boc.nodes() {
+		var bodies []*Boc
+		switch n := node.(type) {
+		case *Return:
+			if len(n.values) > 0 {
+				return n.resultType()
+			}
+		case *When:
+			for _, c := range n.cases {
+				bodies = append(bodies, c.body)
+			}
+		case *Match:
+			for _, c := range n.cases {
+				bodies = append(bodies, c.body)
+			}
+		case *Invocation:
+			if _, body, ok := loopOf(n); ok {
+				bodies = append(bodies, body)
+			}
+		}
+		for _, body := range bodies {
+			if t := earlyReturnType(body); t != nil {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// loop writes a while as a Go for loop. A condition with only one expression is the condition of
+// the for, otherwise it's a function literal invoked in each iteration.
+//
+//	while({ i < 10 }, { i = i + 1 })
+//
+// becomes
+//
+//	for i < 10 {
+//		i = i + 1
+//	}
+func (g *generator) loop(inv *Invocation) {
+	condition, body, _ := loopOf(inv)
+	header := "for "
+	if nodes := condition.nodes(); len(nodes) == 1 {
+		if exp, ok := nodes[0].(expression); ok {
+			header += g.expression(exp) + " "
+		}
+	} else if len(nodes) > 1 {
+		header += g.closure(condition, nil, nil) + "() "
+	}
+	g.labels++
+	l := &goLoop{label: fmt.Sprintf("loop%d", g.labels)}
+	outer := g.out
+	g.out = &strings.Builder{}
+	g.loops = append(g.loops, l)
+	g.block(body, false)
+	g.loops = g.loops[:len(g.loops)-1]
+	code := g.out.String()
+	g.out = outer
+	if l.used {
+		g.line("%s:", l.label)
+	}
+	g.line("%s{", header)
+	g.out.WriteString(code)
+	g.line("}")
+}
+
+// branch writes a break or a continue of the innermost loop. It refers to the loop by its label,
+// inside the switch of a match a break without label would end the switch.
+func (g *generator) branch(node statement, keyword string) {
+	if len(g.loops) == 0 {
+		g.unsupported(node, keyword+" outside a loop")
+		return
+	}
+	l := g.loops[len(g.loops)-1]
+	l.used = true
+	if g.valueCases == 0 {
+		g.line("%s %s", keyword, l.label)
+		return
+	}
+	er := g.earlyReturn
+	er.branchUses++
+	statement := keyword + " " + l.label
+	for _, b := range er.branches {
+		if b.statement == statement {
+			g.line("%s = true", b.flag)
+			g.line("return")
+			return
+		}
+	}
+	g.branches++
+	b := &goBranch{flag: fmt.Sprintf("yz_%s%d", keyword, g.branches), statement: statement}
+	er.branches = append(er.branches, b)
+	g.line("%s = true", b.flag)
+	g.line("return")
+}
+
+// returnStatement writes a return, it ends the Go function of the boc it's in. The cases of a when or
+// a match and the body of a loop are part of that function, unless they are used as a value: then
+// the return ends their function literal and the goReturn is checked after it's invoked.
+func (g *generator) returnStatement(r *Return) {
+	switch {
+	case len(r.values) > 0 && !g.inClosure:
+		g.unsupported(r, "returning values from the file block")
+	case g.valueCases > 0 && len(r.values) > 0 && isTuple(r.resultType()):
+		g.unsupported(r, "returning a tuple from a when or a match used as a value")
+	case g.valueCases > 0:
+		er := g.earlyReturn
+		er.uses++
+		if len(r.values) == 0 {
+			g.line("%s = true", er.returned)
+		} else {
+			er.valueType = r.resultType()
+			g.line("%s, %s = %s, true", er.value, er.returned, g.expression(r.values[0]))
+		}
+		g.line("return")
+	case len(r.values) == 0:
+		g.line("return")
+	default:
+		g.line("return %s", g.values(r.values))
+	}
+}
+
+// caseValue returns the function literal invoked to get the value of the cases of a when or a match.
+// When no case matches the value is the zero value of the type. If the cases return early the
+// statement with the literal is followed by the check of the return, the outermost one declares its
+// variables before the statement:
+//
+//	var yz_returned1 bool
+//	var yz_return1 int
+//	y := func() (result int) {
+//		if k > 3 {
+//			yz_return1, yz_returned1 = 10, true
+//			return
+//		}
+//		...
+//	}()
+//	if yz_returned1 {
+//		return yz_return1
+//	}
+//
+// A break or a continue in the cases sets a variable checked the same way, see goBranch.
+func (g *generator) caseValue(t Type, cases func()) string {
+	outer := g.out
+	g.out = &strings.Builder{}
+	if g.valueCases == 0 {
+		g.returns++
+		g.earlyReturn = &goReturn{returned: fmt.Sprintf("yz_returned%d", g.returns), value: fmt.Sprintf("yz_return%d", g.returns)}
+	}
+	er := g.earlyReturn
+	uses, branchUses := er.uses, er.branchUses
+	g.valueCases++
+	cases()
+	g.valueCases--
+	body := g.out.String()
+	g.out = outer
+	switch {
+	case er.uses == uses:
+	case g.valueCases > 0:
+		// the literal of the enclosing cases ends too
+		g.checks = append(g.checks, "if "+er.returned+" {\nreturn\n}\n")
+	case er.valueType == nil:
+		g.line("var %s bool", er.returned)
+		g.checks = append(g.checks, "if "+er.returned+" {\nreturn\n}\n")
+	default:
+		g.line("var %s bool", er.returned)
+		g.line("var %s %s", er.value, goType(er.valueType))
+		g.checks = append(g.checks, "if "+er.returned+" {\nreturn "+er.value+"\n}\n")
+	}
+	for _, b := range er.branches {
+		switch {
+		case er.branchUses == branchUses:
+		case g.valueCases > 0:
+			g.checks = append(g.checks, "if "+b.flag+" {\nreturn\n}\n")
+		default:
+			g.line("var %s bool", b.flag)
+			g.checks = append(g.checks, "if "+b.flag+" {\n"+b.statement+"\n}\n")
+		}
+	}
+	return "func() (result " + goType(t) + ") {\n" + body + "return\n}()"
+}
+
+// when writes the cases of a when as an if else chain. If result is true the cases return the
+// value of their body.
+func (g *generator) when(w *When, result bool) {
+	for i, c := range w.cases {
+		switch {
+		case c.condition == nil && i == 0:
+			g.line("{")
+		case c.condition == nil:
+			g.line("} else {")
+		case i == 0:
+			g.line("if %s {", g.expression(c.condition))
+		default:
+			g.line("} else if %s {", g.expression(c.condition))
+		}
+		g.block(c.body, result)
+		if c.condition == nil {
+			// the cases after the default are never evaluated
+			break
+		}
+	}
+	g.line("}")
+}
+
+// match writes the cases of a match as a type switch. When the value is a variable the switch
+// declares it again with the type of each case. If result is true the cases return the value of their body.
+func (g *generator) match(m *Match, result bool) {
+	// only the values of an interface type can be switched on
+	value := g.expression(m.value)
+	vt, isVariant := m.value.dataType().(*VariantType)
+	v, narrowed := m.value.(*Variable)
+	switch {
+	case !isVariant:
+		value = "any(" + value + ")"
+	case !narrowed:
+		// the value of a case is a struct, not the interface of the variant
+		value = goType(vt) + "(" + value + ")"
+	}
+	if narrowed {
+		g.line("switch %s := %s.(type) {", goName(v.name), value)
+	} else {
+		g.line("switch %s.(type) {", value)
+	}
+	matched := map[string]bool{}
+	for _, c := range m.cases {
+		if c.pattern == nil {
+			g.line("default:")
+		} else {
+			goCase := goType(c.pattern.varType)
+			if matched[goCase] {
+				// a type already matched is never evaluated again
+				continue
+			}
+			matched[goCase] = true
+			g.line("case %s:", goCase)
+		}
+		if narrowed {
+			g.line("_ = %s", goName(v.name))
+		}
+		g.block(c.body, result)
+		if c.pattern == nil {
+			// the cases after the default are never evaluated
+			break
+		}
+	}
+	g.line("}")
+}
+
+// goOperators are the methods of the basic types that are lowered to Go binary expressions.
+var goOperators = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true,
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"&&": true, "||": true,
+}
+
+func (g *generator) invocation(inv *Invocation) string {
+	if ma, ok := inv.callee.(*MemberAccess); ok {
+		if goOperators[ma.member.name] && len(inv.args) == 1 && len(inv.namedArgs) == 0 {
+			return "(" + g.expression(ma.receiver) + " " + ma.member.name + " " + g.expression(inv.args[0]) + ")"
+		}
+		return g.unsupported(inv, "method invocations")
+	}
+	if bt, ok := inv.callee.dataType().(*BocType); ok && g.generics[bt] != nil {
+		return g.genericCall(inv, bt)
+	}
+	if v, ok := inv.callee.(*Variable); ok && v.name == "print" && isTBD(v.varType) && len(inv.namedArgs) == 0 {
+		// the builtin print writes its arguments in a line, the results of a tuple only if it's the only one
+		for _, arg := range inv.args {
+			if isTuple(arg.dataType()) && len(inv.args) > 1 {
+				return g.unsupported(arg, "printing a tuple with other values")
+			}
+		}
+		g.imports["fmt"] = true
+		return "fmt.Println(" + g.values(inv.args) + ")"
+	}
+	function := g.expression(inv.callee)
+	if strings.HasPrefix(function, "*") {
+		// the element of an array is read through a pointer: (*yzElement(fs, 0, "main.yz:1:1"))()
+		function = "(" + function + ")"
+	}
+	if bt, ok := inv.callee.dataType().(*BocType); ok && bt.signature {
+		return g.call(inv, bt, function)
+	}
+	if len(inv.args) > 0 || len(inv.namedArgs) > 0 {
+		return g.unsupported(inv, "invocations with arguments")
+	}
+	return function + "()"
+}
+
+// call returns the Go call of the function of a block signature, the arguments are passed in the
+// order of its parameters: add(y: 1, x: 2) is add(2, 1).
+func (g *generator) call(inv *Invocation, bt *BocType, function string) string {
+	params := signatureParams(bt)
+	args := make([]string, len(params))
+	for i, a := range inv.args {
+		if i < len(args) {
+			args[i] = g.expression(a)
+		}
+	}
+	for _, na := range inv.namedArgs {
+		for i, v := range params {
+			if v.name == na.name {
+				args[i] = g.expression(na.value)
+			}
+		}
+	}
+	if len(inv.args) > len(params) || slices.Contains(args, "") {
+		return g.unsupported(inv, "invocations without one argument for each parameter")
+	}
+	return function + "(" + strings.Join(args, ", ") + ")"
+}
+
+// genericCall returns the invocation of a generic block. A Go function literal can't have type
+// parameters, so each invocation gets a literal of the block with the type arguments inferred from
+// its arguments: id(1) is func(x int) int { type T = int; return x }(1).
+func (g *generator) genericCall(inv *Invocation, bt *BocType) string {
+	typeArgs := inv.typeArgs(bt)
+	for _, tp := range bt.typeParams {
+		if isTBD(typeArgs[tp]) {
+			return g.unsupported(inv, "generic blocks whose type arguments can't be inferred")
+		}
+	}
+	instance := typeArgs.substitute(bt).(*BocType)
+	return g.call(inv, instance, g.closure(g.generics[bt], instance, typeArgs))
+}
+
+// values returns the Go values of an assignment, a definition or a return. The elements of a single
+// tuple are its values and the results of a block with several are passed as they are: a, b = (1, 2)
+// is a, b = 1, 2 and a, b = swap(a, b) is the same in Go.
+func (g *generator) values(values []expression) string {
+	if pe, ok := valueAt(values, 0).(*ParenthesisExp); ok && len(values) == 1 && len(pe.expressions) > 1 {
+		values = pe.expressions
+	}
+	return strings.Join(g.expressions(values), ", ")
+}
+
+// expressions returns the Go expressions of a list of Yz expressions.
+func (g *generator) expressions(exps []expression) []string {
+	goExps := make([]string, len(exps))
+	for i, e := range exps {
+		goExps[i] = g.expression(e)
+	}
+	return goExps
+}
+
+// instantiation returns the struct literal of a user-defined type or of a case of a variant, with the
+// members passed and the default values of the others: Point(1) is Point{x: 1, y: 0}.
+func (g *generator) instantiation(ti *TypeInstantiation) string {
+	fields := make([]string, 0, len(ti.values)+len(ti.defaults))
+	for _, na := range append(ti.values, ti.defaults...) {
+		fields = append(fields, goName(na.name)+": "+g.expression(na.value))
+	}
+	return goType(ti.instType) + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// hasVariable returns true if the boc type has a variable with the given name.
+func hasVariable(bt *BocType, name string) bool {
+	for _, v := range bt.variables {
+		if v.name == name {
+			return true
+		}
+	}
+	return false
+}
 
-func main() {
-	print("Hello {{.}} code generator")
-}`)
-	if err != nil {
-		return nil, err
+func variantCaseName(vt *VariantType, caseName string) string {
+	if vt.builtin {
+		return "yz_Option_" + caseName + "[" + goType(vt.cases[0].variables[0].varType) + "]"
 	}
+	return goName(vt.name) + "_" + goName(caseName) + goTypeArgs(vt.typeParams, vt.typeArgs)
+}
+
+// goType returns the Go type of a Yz type. The types that are not known yet are any.
+func goType(t Type) string {
+	switch t := t.(type) {
+	case *IntType:
+		return "int"
+	case *DecimalType:
+		return "float64"
+	case *StringType:
+		return "string"
+	case *BoolType:
+		return "bool"
+	case *ArrayType:
+		return "[]" + goType(t.elemType)
+	case *DictType:
+		return "map[" + goType(t.keyType) + "]" + goType(t.valType)
+	case *VariantType:
+		if t.builtin {
+			return "yz_Option[" + goType(t.cases[0].variables[0].varType) + "]"
+		}
+		return goName(t.name) + goTypeArgs(t.typeParams, t.typeArgs)
+	case *GenericType:
+		return goName(t.name)
+	case *BocType:
+		if vt, ok := t.result.(*VariantType); ok && isCaseOf(t, vt) {
+			return variantCaseName(vt, t.name)
+		}
+		if t.name != "" {
+			return goName(t.name) + goTypeArgs(t.typeParams, t.typeArgs)
+		}
+		// the named members of a block signature are the parameters: #(x Int, Int) is func(x int) int
+		params := ""
+		if t.signature {
+			params = goParams(t)
+		}
+		return strings.TrimSpace("func(" + params + ") " + goResult(t))
+	case *TupleType:
+		// the multiple results of a function: #(Int, Int) is func() (int, int)
+		types := make([]string, len(t.elemTypes))
+		for i, et := range t.elemTypes {
+			types[i] = goType(et)
+		}
+		return "(" + strings.Join(types, ", ") + ")"
+	}
+	return "any"
+}
+
+// goKeywords are the Go keywords and the predeclared names, packages and variables used by the
+// generated code.
+var goKeywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true,
+	"defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true, "goto": true,
+	"if": true, "import": true, "interface": true, "map": true, "package": true, "range": true,
+	"return": true, "select": true, "struct": true, "switch": true, "type": true, "var": true,
+	"any": true, "int": true, "float64": true, "string": true, "bool": true, "nil": true,
+	"main": true, "len": true, "append": true, "panic": true,
+	"fmt": true, "strconv": true, "os": true, "result": true,
+}
+
+// goName returns a valid Go identifier for a Yz name. Names that are Go keywords or start with "yz",
+// like the helpers of the generated code, get a "_" suffix and the runes that can't be part of a Go
+// identifier are replaced by their code point: a? is a_u3f.
+func goName(name string) string {
 	var sb strings.Builder
-	err = goSourceTemplate.Execute(&sb, name)
-	if err != nil {
-		return nil, err
+	for i, r := range name {
+		if r == '_' || unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteString(fmt.Sprintf("_u%x", r))
+		}
+	}
+	if goKeywords[name] || strings.HasPrefix(name, "yz") {
+		sb.WriteString("_")
+	}
+	return sb.String()
+}
+
+// goNames returns the Go names of the variables.
+func goNames(variables []*Variable) []string {
+	names := make([]string, len(variables))
+	for i, v := range variables {
+		names[i] = goName(v.name)
 	}
-	return []byte(sb.String()), nil
+	return names
 }
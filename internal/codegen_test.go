@@ -0,0 +1,1024 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "Variables and operators",
+			source: `a: 1 + 2 * 3
+b String = "hi"
+c: d: [1, 2]
+a = 4`,
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	a := ((1 + 2) * 3)
+	_ = a
+	var b string = "hi"
+	_ = b
+	d := []int{1, 2}
+	_ = d
+	c := d
+	_ = c
+	a = 4
+}
+`,
+		},
+		{
+			name:   "Nested types",
+			source: "grid: [][]Int\ngroups: [String][]Int\ncounts [][String:Int]",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	grid := [][]int{}
+	_ = grid
+	groups := map[string][]int{}
+	_ = groups
+	var counts []map[string]int
+	_ = counts
+}
+`,
+		},
+		{
+			name:   "Block",
+			source: `language: { name: "Yz" }`,
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	language := func() string {
+		name := "Yz"
+		_ = name
+		return name
+	}
+	_ = language
+}
+`,
+		},
+		{
+			name:   "Blocks with parameters",
+			source: "add #(x Int, y Int, Int) = { x + y }\ntwice #(n Int, Int) = { n * 2 }\napply #(f #(n Int, Int), n Int, Int) = { f(n) }\na: add(1, 2)\nb: add(y: 3, x: 4)\nprint(\"sum\", a + b, apply(twice, 5))",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+)
+
+func main() {
+	var add func(x int, y int) int = func(x int, y int) int {
+		return (x + y)
+	}
+	_ = add
+	var twice func(n int) int = func(n int) int {
+		return (n * 2)
+	}
+	_ = twice
+	var apply func(f func(n int) int, n int) int = func(f func(n int) int, n int) int {
+		return f(n)
+	}
+	_ = apply
+	a := add(1, 2)
+	_ = a
+	b := add(4, 3)
+	_ = b
+	fmt.Println("sum", (a + b), apply(twice, 5))
+}
+`,
+		},
+		{
+			name:   "Block signature without a block",
+			source: "f #(x Int, Int)\ng #(x Int, String, Bool)\nh #()",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	var f func(x int) int = func(x int) (yz_result1 int) {
+		return
+	}
+	_ = f
+	var g func(x int) (string, bool) = func(x int) (yz_result1 string, yz_result2 bool) {
+		return
+	}
+	_ = g
+	var h func() = func() {
+	}
+	_ = h
+}
+`,
+		},
+		{
+			name:   "Arrays of blocks with a signature",
+			source: "twice #(n Int, Int) = { n * 2 }\nfs []#(n Int, Int) = [twice]\npairs []#(Int, Int)\nprint(fs[0](1))",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// yzElement returns the element i of a. If i is out of range it exits with the position of the index in the Yz source.
+func yzElement[T any](a []T, i int, position string) *T {
+	if i < 0 || i >= len(a) {
+		fmt.Fprintf(os.Stderr, "%s: index %d out of range [0:%d]\n", position, i, len(a))
+		os.Exit(2)
+	}
+	return &a[i]
+}
+
+func main() {
+	var twice func(n int) int = func(n int) int {
+		return (n * 2)
+	}
+	_ = twice
+	var fs []func(n int) int = []func(n int) int{twice}
+	_ = fs
+	var pairs []func() (int, int)
+	_ = pairs
+	fmt.Println((*yzElement(fs, 0, "main.yz:4:10"))(1))
+}
+`,
+		},
+		{
+			name:   "Tuples",
+			source: "swap #(a Int, b Int, Int, Int) = { (b, a) }\norder #(a Int, b Int, Int, Int) = {\n    when { a > b => return b, a }\n    return a, b\n}\nx Int, y Int = swap(1, 2)\nx, y = (3, 4)\npair: { (1, \"one\") }\nn Int\ns String\nn, s = pair()\nprint(order(x, y))",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+)
+
+func main() {
+	var swap func(a int, b int) (int, int) = func(a int, b int) (int, int) {
+		return b, a
+	}
+	_ = swap
+	var order func(a int, b int) (int, int) = func(a int, b int) (int, int) {
+		if a > b {
+			return b, a
+		}
+		return a, b
+	}
+	_ = order
+	var x int
+	var y int
+	x, y = swap(1, 2)
+	_ = x
+	_ = y
+	x, y = 3, 4
+	pair := func() (int, string) {
+		return 1, "one"
+	}
+	_ = pair
+	var n int
+	_ = n
+	var s string
+	_ = s
+	n, s = pair()
+	fmt.Println(order(x, y))
+}
+`,
+		},
+		{
+			name:   "Assignment used as a value",
+			source: "a: 1\nb: a = 2\nd: [\"k\": 1]\ne: d[\"j\"] = 3",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	a := 1
+	_ = a
+	b := func() int {
+		a = 2
+		return a
+	}()
+	_ = b
+	d := map[string]int{"k": 1}
+	_ = d
+	e := func() int {
+		yz_value := 3
+		d["j"] = yz_value
+		return yz_value
+	}()
+	_ = e
+}
+`,
+		},
+		{
+			name:   "Self-referential type",
+			source: "Node #(value Int, next []Node)\nleaf: Node(2, []Node)\nn: Node(1, [leaf])",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+type Node struct {
+	value int
+	next  []Node
+}
+
+func main() {
+	leaf := Node{value: 2, next: []Node{}}
+	_ = leaf
+	n := Node{value: 1, next: []Node{leaf}}
+	_ = n
+}
+`,
+		},
+		{
+			name:   "Recursive variant",
+			source: "List { Cons(head Int, tail List), Empty() }\nl List = Cons(1, Cons(2, Empty()))\nh: l match { Cons => l.head }, { Empty => 0 }",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+// List is a variant, its values are one of the List_ structs.
+type List interface {
+	isList()
+}
+
+type List_Cons struct {
+	head int
+	tail List
+}
+
+func (List_Cons) isList() {}
+
+type List_Empty struct {
+}
+
+func (List_Empty) isList() {}
+
+func main() {
+	var l List = List_Cons{head: 1, tail: List_Cons{head: 2, tail: List_Empty{}}}
+	_ = l
+	h := func() (result int) {
+		switch l := l.(type) {
+		case List_Cons:
+			_ = l
+			return l.head
+		case List_Empty:
+			_ = l
+			return 0
+		}
+		return
+	}()
+	_ = h
+}
+`,
+		},
+		{
+			name:   "Recursive generic types",
+			source: "List { Cons(head T, tail List(T)), Empty() }\nl List(Int) = Cons(1, Cons(2, Empty()))\nm: Cons(\"a\", Empty())\nTree #(value T, children []Tree(T))\nt: Tree(1, [Tree(2, []Tree(Int))])",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+// List is a variant, its values are one of the List_ structs.
+type List[T any] interface {
+	isList()
+}
+
+type List_Cons[T any] struct {
+	head T
+	tail List[T]
+}
+
+func (List_Cons[T]) isList() {}
+
+type List_Empty[T any] struct {
+}
+
+func (List_Empty[T]) isList() {}
+
+type Tree[T any] struct {
+	value    T
+	children []Tree[T]
+}
+
+func main() {
+	var l List[int] = List_Cons[int]{head: 1, tail: List_Cons[int]{head: 2, tail: List_Empty[int]{}}}
+	_ = l
+	var m List[string] = List_Cons[string]{head: "a", tail: List_Empty[string]{}}
+	_ = m
+	t := Tree[int]{value: 1, children: []Tree[int]{Tree[int]{value: 2, children: []Tree[int]{}}}}
+	_ = t
+}
+`,
+		},
+		{
+			name: "Variant",
+			source: `Option {
+    Some(value T),
+    None()
+}
+a: Some(1)
+b Option(Int) = None()`,
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+// Option is a variant, its values are one of the Option_ structs.
+type Option[T any] interface {
+	isOption()
+}
+
+type Option_Some[T any] struct {
+	value T
+}
+
+func (Option_Some[T]) isOption() {}
+
+type Option_None[T any] struct {
+}
+
+func (Option_None[T]) isOption() {}
+
+func main() {
+	var a Option[int] = Option_Some[int]{value: 1}
+	_ = a
+	var b Option[int] = Option_None[int]{}
+	_ = b
+}
+`,
+		},
+		{
+			name:   "Variable of a variant case",
+			source: "Option { Some(value Int), None() }\no: Some(1)\no = None()",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+// Option is a variant, its values are one of the Option_ structs.
+type Option interface {
+	isOption()
+}
+
+type Option_Some struct {
+	value int
+}
+
+func (Option_Some) isOption() {}
+
+type Option_None struct {
+}
+
+func (Option_None) isOption() {}
+
+func main() {
+	var o Option = Option_Some{value: 1}
+	_ = o
+	o = Option_None{}
+}
+`,
+		},
+		{
+			name: "Type instantiation",
+			source: `Point #(x Int, y Int)
+Size: { width: 1, height: 2 }
+a: Point(1, 2)
+b: Point(y: 3, x: 4)
+c: Size(height: 5)`,
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+type Point struct {
+	x int
+	y int
+}
+
+type Size struct {
+	width  int
+	height int
+}
+
+func main() {
+	a := Point{x: 1, y: 2}
+	_ = a
+	b := Point{x: 4, y: 3}
+	_ = b
+	c := Size{height: 5, width: 1}
+	_ = c
+}
+`,
+		},
+		{
+			name: "Generics",
+			source: `Option {
+    Some(value T),
+    None()
+}
+Pair #(A, B, first A, second B)
+p: Pair(1, "one")
+o Option(Pair(Int, String)) = None()
+o = Some(p)
+n: o match { Some => o.value.second }, { None => "none" }`,
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+// Option is a variant, its values are one of the Option_ structs.
+type Option[T any] interface {
+	isOption()
+}
+
+type Option_Some[T any] struct {
+	value T
+}
+
+func (Option_Some[T]) isOption() {}
+
+type Option_None[T any] struct {
+}
+
+func (Option_None[T]) isOption() {}
+
+type Pair[A any, B any] struct {
+	first  A
+	second B
+}
+
+func main() {
+	p := Pair[int, string]{first: 1, second: "one"}
+	_ = p
+	var o Option[Pair[int, string]] = Option_None[Pair[int, string]]{}
+	_ = o
+	o = Option_Some[Pair[int, string]]{value: p}
+	n := func() (result string) {
+		switch o := o.(type) {
+		case Option_Some[Pair[int, string]]:
+			_ = o
+			return o.value.second
+		case Option_None[Pair[int, string]]:
+			_ = o
+			return "none"
+		}
+		return
+	}()
+	_ = n
+}
+`,
+		},
+		{
+			name:   "Generic blocks",
+			source: "id #(T, x T, T) = { x }\nwrap #(T, x T, []T) = { [x] }\nn: id(1)\nw: wrap(x: \"one\")",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	n := func(x int) int {
+		type T = int
+		return x
+	}(1)
+	_ = n
+	w := func(x string) []string {
+		type T = string
+		return []T{x}
+	}("one")
+	_ = w
+}
+`,
+		},
+		{
+			name:   "Single letter variables",
+			source: "N: 10\nX Int = 1\nX = N + 1\nid #(T, x T, T) = { x }\nprint(X, id(N))",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+)
+
+func main() {
+	N := 10
+	_ = N
+	var X int = 1
+	_ = X
+	X = (N + 1)
+	fmt.Println(X, func(x int) int {
+		type T = int
+		return x
+	}(N))
+}
+`,
+		},
+		{
+			name:    "Generic block without a body",
+			source:  "id #(T, x T, T)",
+			wantErr: "[main.yz: line: 1 col: 1] code generation of generic blocks without a body is not supported yet",
+		},
+		{
+			name:   "String interpolation",
+			source: "n: 3\nname: 'Yz'\nd: 1.5\nok: n > 1\na: [1, 2]\ns: 'Hello, `name`! `n + 1`st, `d` `ok` `a` \\`'\nt: \"`name`\"",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func main() {
+	n := 3
+	_ = n
+	name := "Yz"
+	_ = name
+	d := 1.5
+	_ = d
+	ok := (n > 1)
+	_ = ok
+	a := []int{1, 2}
+	_ = a
+	s := "Hello, " + name + "! " + strconv.Itoa((n + 1)) + "st, " + strconv.FormatFloat(d, 'g', -1, 64) + " " + strconv.FormatBool(ok) + " " + fmt.Sprint(a) + " ` + "`" + `"
+	_ = s
+	t := name
+	_ = t
+}
+`,
+		},
+		{
+			name: "When",
+			source: `n: 3
+x: when
+    { n == 0 => 1 }
+    { n > 0 => n * 2 }
+    { _ => 0 }
+when { n > 1 => n = 1 }`,
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	n := 3
+	_ = n
+	x := func() (result int) {
+		if n == 0 {
+			return 1
+		} else if n > 0 {
+			return (n * 2)
+		} else {
+			return 0
+		}
+		return
+	}()
+	_ = x
+	if n > 1 {
+		n = 1
+	}
+}
+`,
+		},
+		{
+			name:   "Booleans",
+			source: "ready: false\nx: when { ready => 1 }, { _ => 2 }\ndone: ready == true",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	ready := false
+	_ = ready
+	x := func() (result int) {
+		if ready {
+			return 1
+		} else {
+			return 2
+		}
+		return
+	}()
+	_ = x
+	done := (ready == true)
+	_ = done
+}
+`,
+		},
+		{
+			name: "Match",
+			source: `Option {
+    Some(value Int),
+    None()
+}
+opt Option = Some(1)
+x: opt match
+    { Some => opt.value }
+    { None => 0 }
+Some(2) match { None => x = 0 }, { _ => x = 1 }`,
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+// Option is a variant, its values are one of the Option_ structs.
+type Option interface {
+	isOption()
+}
+
+type Option_Some struct {
+	value int
+}
+
+func (Option_Some) isOption() {}
+
+type Option_None struct {
+}
+
+func (Option_None) isOption() {}
+
+func main() {
+	var opt Option = Option_Some{value: 1}
+	_ = opt
+	x := func() (result int) {
+		switch opt := opt.(type) {
+		case Option_Some:
+			_ = opt
+			return opt.value
+		case Option_None:
+			_ = opt
+			return 0
+		}
+		return
+	}()
+	_ = x
+	switch Option(Option_Some{value: 2}).(type) {
+	case Option_None:
+		x = 0
+	default:
+		x = 1
+	}
+}
+`,
+		},
+		{
+			name:   "Match on a variable of a case",
+			source: "Option { Some(value Int), None() }\no: Some(1)\nr: o match { Some => o.value }, { None => 0 }",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+// Option is a variant, its values are one of the Option_ structs.
+type Option interface {
+	isOption()
+}
+
+type Option_Some struct {
+	value int
+}
+
+func (Option_Some) isOption() {}
+
+type Option_None struct {
+}
+
+func (Option_None) isOption() {}
+
+func main() {
+	var o Option = Option_Some{value: 1}
+	_ = o
+	r := func() (result int) {
+		switch o := o.(type) {
+		case Option_Some:
+			_ = o
+			return o.value
+		case Option_None:
+			_ = o
+			return 0
+		}
+		return
+	}()
+	_ = r
+}
+`,
+		},
+		{
+			name: "Loops and returns",
+			source: `i: 0
+while({ i < 10 }, {
+    i = i + 1
+    when { i == 2 => continue }
+})
+first: {
+    n: 0
+    while({ n < 100 }, {
+        n = n + 1
+        when { n > 3 => return n }
+        when { n > 50 => break }
+    })
+}
+sign: {
+    when { i < 0 => return "negative" }
+    "positive"
+}
+when { i > 100 => return }`,
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	i := 0
+	_ = i
+loop1:
+	for i < 10 {
+		i = (i + 1)
+		if i == 2 {
+			continue loop1
+		}
+	}
+	first := func() (result int) {
+		n := 0
+		_ = n
+	loop2:
+		for n < 100 {
+			n = (n + 1)
+			if n > 3 {
+				return n
+			}
+			if n > 50 {
+				break loop2
+			}
+		}
+		return
+	}
+	_ = first
+	sign := func() string {
+		if i < 0 {
+			return "negative"
+		}
+		return "positive"
+	}
+	_ = sign
+	if i > 100 {
+		return
+	}
+}
+`,
+		},
+		{
+			name: "Indexing",
+			source: `a: [1, 2]
+a[0] = a[1]
+d: ["one": 1]
+d["two": 2]
+n: d["one"] match { Some => 1 }, { None => 0 }`,
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// yz_Option is the Option of a dictionary read, its values are one of the yz_Option_ structs.
+type yz_Option[T any] interface {
+	isOption()
+}
+
+type yz_Option_Some[T any] struct {
+	value T
+}
+
+func (yz_Option_Some[T]) isOption() {}
+
+type yz_Option_None[T any] struct {
+}
+
+func (yz_Option_None[T]) isOption() {}
+
+// yzElement returns the element i of a. If i is out of range it exits with the position of the index in the Yz source.
+func yzElement[T any](a []T, i int, position string) *T {
+	if i < 0 || i >= len(a) {
+		fmt.Fprintf(os.Stderr, "%s: index %d out of range [0:%d]\n", position, i, len(a))
+		os.Exit(2)
+	}
+	return &a[i]
+}
+
+// yzLookup returns the value of the key in d as an Option, None if d doesn't have the key.
+func yzLookup[K comparable, V any](d map[K]V, key K) yz_Option[V] {
+	if v, ok := d[key]; ok {
+		return yz_Option_Some[V]{value: v}
+	}
+	return yz_Option_None[V]{}
+}
+
+func main() {
+	a := []int{1, 2}
+	_ = a
+	*yzElement(a, 0, "main.yz:2:3") = *yzElement(a, 1, "main.yz:2:10")
+	d := map[string]int{"one": 1}
+	_ = d
+	d["two"] = 2
+	n := func() (result int) {
+		switch yz_Option[int](yzLookup(d, "one")).(type) {
+		case yz_Option_Some[int]:
+			return 1
+		case yz_Option_None[int]:
+			return 0
+		}
+		return
+	}()
+	_ = n
+}
+`,
+		},
+		{
+			name:   "Return in a when used as a value",
+			source: "f: {\n    a: when { 1 > 0 => return 1 }, { _ => 2 }\n    a\n}",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	f := func() int {
+		var yz_returned1 bool
+		var yz_return1 int
+		a := func() (result int) {
+			if 1 > 0 {
+				yz_return1, yz_returned1 = 1, true
+				return
+			} else {
+				return 2
+			}
+			return
+		}()
+		_ = a
+		if yz_returned1 {
+			return yz_return1
+		}
+		return a
+	}
+	_ = f
+}
+`,
+		},
+		{
+			name:   "Break and continue in a when used as a value",
+			source: "i: 0\nwhile({ i < 10 }, {\n    i = i + 1\n    x: when { i == 2 => continue }, { i > 5 => break }, { _ => i }\n    print(x)\n})",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+)
+
+func main() {
+	i := 0
+	_ = i
+loop1:
+	for i < 10 {
+		i = (i + 1)
+		var yz_continue1 bool
+		var yz_break2 bool
+		x := func() (result int) {
+			if i == 2 {
+				yz_continue1 = true
+				return
+			} else if i > 5 {
+				yz_break2 = true
+				return
+			} else {
+				return i
+			}
+			return
+		}()
+		_ = x
+		if yz_continue1 {
+			continue loop1
+		}
+		if yz_break2 {
+			break loop1
+		}
+		fmt.Println(x)
+	}
+}
+`,
+		},
+		{
+			name:   "Return in nested cases used as a value",
+			source: "g #(k Int, Int) = {\n    when { k > 0 => when { k > 5 => return 100 }, { _ => 1 } }, { _ => 0 }\n}",
+			want: `// Code generated by yzc as main.go. DO NOT EDIT.
+
+package main
+
+func main() {
+	var g func(k int) int = func(k int) int {
+		var yz_returned1 bool
+		var yz_return1 int
+		yz_value := func() (result int) {
+			if k > 0 {
+				yz_value := func() (result int) {
+					if k > 5 {
+						yz_return1, yz_returned1 = 100, true
+						return
+					} else {
+						return 1
+					}
+					return
+				}()
+				if yz_returned1 {
+					return
+				}
+				return yz_value
+			} else {
+				return 0
+			}
+			return
+		}()
+		if yz_returned1 {
+			return yz_return1
+		}
+		return yz_value
+	}
+	_ = g
+}
+`,
+		},
+		{
+			name:    "Missing argument",
+			source:  "add #(x Int, y Int, Int) = { x + y }\na: add(1)",
+			wantErr: "[main.yz: line: 2 col: 4] code generation of invocations without one argument for each parameter is not supported yet",
+		},
+		{
+			name:   "Tuples that can't be lowered",
+			source: "pair: { (1, 2) }\nt: pair()\nprint(1, pair())\nn: 1\nf: { x: when { n > 0 => when { n > 5 => return 1, 2 }; 7 }, { _ => 3 }; (x, x) }\ng: { when { n > 0 => (1, 2) }, { _ => (3, 4) } }",
+			wantErr: "[main.yz: line: 2 col: 1] code generation of variables holding a tuple is not supported yet\n" +
+				"[main.yz: line: 3 col: 10] code generation of printing a tuple with other values is not supported yet\n" +
+				"[main.yz: line: 5 col: 41] code generation of returning a tuple from a when or a match used as a value is not supported yet\n" +
+				"[main.yz: line: 6 col: 6] code generation of a when whose value is a tuple is not supported yet",
+		},
+		{
+			name:    "Return in an assignment used as a value",
+			source:  "f: { n: 1; b: n = when { n > 0 => return 5 }, { _ => 2 }; b }",
+			wantErr: "[main.yz: line: 1 col: 15] code generation of a return in the value of an assignment used as a value is not supported yet",
+		},
+		{
+			name:    "Unsupported construct",
+			source:  "a: 1\na.b",
+			wantErr: "[main.yz: line: 2 col: 1] code generation of member access on values of type Int is not supported yet",
+		},
+		{
+			name:    "Member access on a block",
+			source:  "language: { name: \"Yz\" }\nprint(language.name)",
+			wantErr: "[main.yz: line: 2 col: 7] code generation of member access on values of type #(name String, String) is not supported yet",
+		},
+		{
+			name:   "Methods of a type",
+			source: "Point #(x Int, y Int) = {\n    norm: { x * x + y * y }\n}\np: Point(1, 2)\nprint(p.norm())",
+			wantErr: "[main.yz: line: 1 col: 1] code generation of the methods of a type is not supported yet\n" +
+				"[main.yz: line: 5 col: 7] code generation of method invocations is not supported yet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := &fileSet{}
+			tokens, err := Tokenize(files, []string{"main.yz"}, tt.source)
+			if err != nil {
+				t.Errorf("Tokenize() error = \"%v\"", err)
+				return
+			}
+			boc, err := Parse(files, []string{"main.yz"}, tokens)
+			if err != nil {
+				t.Errorf("Parse() error = \"%v\"", err)
+				return
+			}
+			got, err := Bytes(files, boc, "main.go")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Bytes() error = \"%v\", want \"%s\"", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Bytes() error = \"%v\"", err)
+				return
+			}
+			if strings.TrimSpace(string(got)) != strings.TrimSpace(tt.want) {
+				t.Errorf("Bytes() = \n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
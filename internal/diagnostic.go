@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+)
+
+// Severity tells how serious a Diagnostic is.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+// Diagnostic codes, grouped by the compilation phase that reports them.
+const (
+	codeRead     = "E0001" // the source file can't be read
+	codeLexical  = "E0100" // the tokenizer found invalid input
+	codeSyntax   = "E0200" // the parser found an unexpected token
+	codeType     = "E0300" // the types or the number of values of an expression don't match
+	codeSemantic = "E0400" // the checker found an invalid use of a construct, like a match that misses cases
+	codeCodegen  = "E0800" // the Go source couldn't be generated or written
+	codeGoBuild  = "E0900" // the generated Go source didn't compile
+)
+
+// Diagnostic is a problem found while compiling a source file.
+// It implements error so the phases can return it as such.
+type Diagnostic struct {
+	File     string
+	span     span
+	Severity Severity
+	Code     string
+	Message  string
+	Hint     string
+	labels   []label
+	sources  *fileSet // the files of the build the spans belong to, nil until it's added to a Diagnostics
+}
+
+// label points at a secondary span related to a Diagnostic, e.g. where an unclosed block was opened.
+type label struct {
+	span    span
+	message string
+}
+
+// Diagnostics collects the diagnostics of every file and phase of a compilation.
+// It implements error so a phase can report all its problems at once.
+type Diagnostics struct {
+	list    []*Diagnostic
+	sources *fileSet // the files the spans of the diagnostics added are resolved with
+}
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func newDiagnostic(span span, code, message string) *Diagnostic {
+	return &Diagnostic{
+		span:     span,
+		Severity: SeverityError,
+		Code:     code,
+		Message:  message,
+	}
+}
+
+// NewSourceError returns the error of a source root or a source file that can't be compiled, like
+// a directory that doesn't exist. It has no position, path is where the problem was found.
+func NewSourceError(path, message, hint string) *Diagnostic {
+	return &Diagnostic{File: path, Severity: SeverityError, Code: codeRead, Message: message, Hint: hint}
+}
+
+// withLabel adds a secondary label to the diagnostic and returns it.
+func (d *Diagnostic) withLabel(span span, message string) *Diagnostic {
+	d.labels = append(d.labels, label{span, message})
+	return d
+}
+
+// withHint sets the hint of the diagnostic and returns it.
+func (d *Diagnostic) withHint(hint string) *Diagnostic {
+	d.Hint = hint
+	return d
+}
+
+func (d *Diagnostic) Error() string {
+	var sb strings.Builder
+	location := d.fileName()
+	if p := d.sources.position(d.span); p != (position{}) {
+		if location != "" {
+			location += ": "
+		}
+		location += p.String()
+	}
+	if location != "" {
+		sb.WriteString("[" + location + "] ")
+	}
+	sb.WriteString(d.Message)
+	if d.Hint != "" {
+		sb.WriteString("\nHint: " + d.Hint)
+	}
+	return sb.String()
+}
+
+// fileName returns the File of the diagnostic or, if it is not set, the name of the file of its span.
+func (d *Diagnostic) fileName() string {
+	if d.File != "" {
+		return d.File
+	}
+	return d.sources.fileName(d.span)
+}
+
+// Add appends the diagnostics to the bag. The ones without files resolve their spans with the
+// files of the bag.
+func (ds *Diagnostics) Add(d ...*Diagnostic) {
+	for _, diagnostic := range d {
+		if diagnostic.sources == nil {
+			diagnostic.sources = ds.sources
+		}
+	}
+	ds.list = append(ds.list, d...)
+}
+
+// addError adds e to the bag as one or more diagnostics of the given file.
+// e can be a *Diagnostic, a *Diagnostics or any other error, in which case
+// it is reported with the given code and no position.
+func (ds *Diagnostics) addError(file, code string, e error) {
+	var all *Diagnostics
+	var single *Diagnostic
+	switch {
+	case errors.As(e, &all):
+		for _, d := range all.list {
+			ds.addError(file, code, d)
+		}
+	case errors.As(e, &single):
+		if single.File == "" {
+			single.File = file
+		}
+		ds.Add(single)
+	default:
+		ds.Add(&Diagnostic{File: file, Severity: SeverityError, Code: code, Message: e.Error()})
+	}
+}
+
+// All returns the diagnostics in the order they were added.
+func (ds *Diagnostics) All() []*Diagnostic {
+	return ds.list
+}
+
+// HasErrors returns true if any of the diagnostics is an error.
+func (ds *Diagnostics) HasErrors() bool {
+	for _, d := range ds.list {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (ds *Diagnostics) Error() string {
+	messages := make([]string, len(ds.list))
+	for i, d := range ds.list {
+		messages[i] = d.Error()
+	}
+	return strings.Join(messages, "\n")
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagnostic_Error(t *testing.T) {
+	files := &fileSet{}
+	file := files.add("a/b.yz", "1 2\nx: \"abc\n`")
+	tests := []struct {
+		name       string
+		diagnostic *Diagnostic
+		want       string
+	}{
+		{
+			"Span",
+			newDiagnostic(span{file, 2, 3}, codeSyntax, "expected \",\" or \"}\". Got \"2\""),
+			"[a/b.yz: line: 1 col: 3] expected \",\" or \"}\". Got \"2\"",
+		},
+		{
+			"File overrides the span file name",
+			&Diagnostic{File: "c.yz", span: span{file, 7, 11}, Code: codeLexical, Message: "unterminated string literal"},
+			"[c.yz: line: 2 col: 4] unterminated string literal",
+		},
+		{
+			"File without span",
+			&Diagnostic{File: "a/b.yz", Code: codeRead, Message: "permission denied"},
+			"[a/b.yz] permission denied",
+		},
+		{
+			"No file and no span",
+			&Diagnostic{Code: codeCodegen, Message: "no space left on device"},
+			"no space left on device",
+		},
+		{
+			"Hint",
+			&Diagnostic{span: span{file, 12, 13}, Message: "unexpected character `", Hint: "use \" or ' to quote strings"},
+			"[a/b.yz: line: 3 col: 1] unexpected character `\nHint: use \" or ' to quote strings",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the diagnostic resolves its span with the files of the bag it's added to
+			(&Diagnostics{sources: files}).Add(tt.diagnostic)
+			if got := tt.diagnostic.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiagnostics_AddError(t *testing.T) {
+	files := &fileSet{}
+	a := files.add("a.yz", "1 2")
+	b := files.add("b.yz", "x\ny")
+	ds := &Diagnostics{sources: files}
+	ds.addError("a.yz", codeSyntax, newDiagnostic(span{a, 2, 3}, codeSyntax, "first"))
+	ds.addError("b.yz", codeLexical, &Diagnostics{list: []*Diagnostic{
+		newDiagnostic(span{b, 0, 1}, codeLexical, "second"),
+		{File: "c.yz", span: span{b, 2, 3}, Severity: SeverityWarning, Code: codeLexical, Message: "third"},
+	}})
+	ds.addError("d.yz", codeRead, errors.New("fourth"))
+
+	want := []string{
+		"[a.yz: line: 1 col: 3] first",
+		"[b.yz: line: 1 col: 1] second",
+		"[c.yz: line: 2 col: 1] third",
+		"[d.yz] fourth",
+	}
+	if len(ds.All()) != len(want) {
+		t.Fatalf("All() = %v, want %v", ds.All(), want)
+	}
+	for i, d := range ds.All() {
+		if d.Error() != want[i] {
+			t.Errorf("All()[%d] = %q, want %q", i, d.Error(), want[i])
+		}
+	}
+	if ds.All()[3].Code != codeRead {
+		t.Errorf("Code = %s, want %s", ds.All()[3].Code, codeRead)
+	}
+	if !ds.HasErrors() {
+		t.Errorf("HasErrors() = false, want true")
+	}
+	if (&Diagnostics{list: []*Diagnostic{ds.All()[2]}}).HasErrors() {
+		t.Errorf("HasErrors() = true for a warning, want false")
+	}
+}
+
+func TestCheck_CollectsAllFiles(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"good.yz":    `a: 1`,
+		"syntax.yz":  `1 2`,
+		"lexical.yz": `"unterminated`,
+	}
+	var input []SourceFile
+	for _, name := range []string{"good.yz", "syntax.yz", "lexical.yz"} {
+		path := filepath.Join(dir, name)
+		if e := os.WriteFile(path, []byte(sources[name]), 0600); e != nil {
+			t.Fatal(e)
+		}
+		input = append(input, NewSourceFile(dir, name, path))
+	}
+	input = append(input, NewSourceFile(dir, "missing.yz", filepath.Join(dir, "missing.yz")))
+
+	ds := Check(input)
+	wantCodes := []string{codeSyntax, codeLexical, codeRead}
+	if len(ds.All()) != len(wantCodes) {
+		t.Fatalf("Check() = %v, want %d diagnostics", ds, len(wantCodes))
+	}
+	for i, d := range ds.All() {
+		if d.Code != wantCodes[i] {
+			t.Errorf("Check()[%d].Code = %s, want %s (%v)", i, d.Code, wantCodes[i], d)
+		}
+	}
+	if ds.All()[0].File != "syntax.yz" {
+		t.Errorf("Check()[0].File = %s, want syntax.yz", ds.All()[0].File)
+	}
+}
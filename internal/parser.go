@@ -9,16 +9,24 @@ type parser struct {
 	tokens       []Token
 	currentIndex int
 	Token
-	prog *Boc
+	prog        *Boc
+	diagnostics Diagnostics
+	prevEnd     int                           // end offset of the last consumed token
+	parens      int                           // number of argument lists being parsed in the current block
+	lists       int                           // number of arrays, dictionaries and multiple assignments being parsed in the current block
+	scopes      []map[string]*Variable        // variables declared in the enclosing blocks, innermost last
+	types       map[*BocType]*TypeDeclaration // the declarations of the user-defined types, for their default values
+	generics    *[]*GenericType               // type parameters of the generic declaration being parsed, nil outside of it
 }
 
-func Parse(parents []string, tokens []Token) (*Boc, error) {
-	p := newParser(tokens)
-	debugCurrentTokenAtPosition(tokens, 0)
-	leaf, e := p.boc()
-	if e != nil {
-		return nil, e
-	}
+// Parse creates the AST of the tokens of a source file and wraps it in the intermediate parents.
+// The parser recovers from syntax errors, so it always returns an AST where the invalid parts are
+// replaced by BadExpr nodes, and a *Diagnostics error with all the syntax errors found, if any.
+// The spans of the errors are resolved with files, where the file was tokenized.
+func Parse(files *fileSet, parents []string, tokens []Token) (*Boc, error) {
+	p := newParser(files, tokens)
+	debugCurrentTokenAtPosition(files, tokens, 0)
+	leaf := p.boc(EOF)
 	// Creates the intermediate parents.
 
 	for i := len(parents) - 1; i >= 0; i-- {
@@ -27,31 +35,42 @@ func Parse(parents []string, tokens []Token) (*Boc, error) {
 		leaf = &Boc{
 			expressions: []expression{
 				&ShortDeclaration{
-					pos:      pos(0, 0),
-					variable: &Variable{pos(0, 0), name, newBocType()},
+					span:     span{},
+					variable: &Variable{span{}, name, newBocType()},
 					value:    leaf,
 				},
 			},
 			statements: []statement{},
 		}
 	}
+	if len(p.diagnostics.list) > 0 {
+		return leaf, &p.diagnostics
+	}
 	return leaf, nil
 }
 
-func newParser(tokens []Token) *parser {
+func newParser(files *fileSet, tokens []Token) *parser {
 	return &parser{
 		tokens,
 		0,
 		tokens[0],
 		nil,
+		Diagnostics{sources: files},
+		0,
+		0,
+		0,
+		nil,
+		map[*BocType]*TypeDeclaration{},
+		nil,
 	}
 }
 
 // consume advances the parser by one Token.
 func (p *parser) consume() {
+	p.prevEnd = p.span.end
 	p.currentIndex++
 	if p.currentIndex >= len(p.tokens) {
-		p.Token = Token{p.pos, EOF, "EOF"}
+		p.Token = Token{p.span, EOF, "EOF"}
 	} else {
 		p.Token = p.tokens[p.currentIndex]
 	}
@@ -66,47 +85,171 @@ func (p *parser) expect(t tokenType) error {
 }
 
 // block_body ::= (expression | statement) ((","|"\n") (expression | statement))* | ""
-func (p *parser) boc() (*Boc, error) {
+//
+// closing is the token that ends the block: RBRACE for a block literal and EOF for a whole file.
+// Syntax errors are reported and replaced by BadExpr or BadStmt nodes, and the parsing continues
+// after the next "," or the end of the block.
+func (p *parser) boc(closing tokenType) *Boc {
+	start := p.span
+	if closing == EOF {
+		// the block of a file starts at the beginning of the file
+		start = span{p.span.file, 0, 0}
+	} else if p.currentIndex > 0 {
+		// include the { already consumed
+		start = p.tokens[p.currentIndex-1].span
+	}
 	bb := &Boc{
+		span{},
 		[]expression{},
 		[]statement{},
 	}
+	// a ")" inside the block doesn't close the argument lists around it and
+	// a "," separates the expressions of the block, not the elements of the lists around it
+	parens, lists := p.parens, p.lists
+	p.parens, p.lists = 0, 0
+	defer func() { p.parens, p.lists = parens, lists }()
+	p.scopes = append(p.scopes, map[string]*Variable{})
+	defer func() { p.scopes = p.scopes[:len(p.scopes)-1] }()
 	// Checks if there is an expression or a statement
 	// if there's an expression adds it to the expressions slice
 	// if there's a statement adds it to the statements slice
 	// if there's a comma or newline, it continues to parse the next expression or statement
-	// Checks if there is an expression or a statement
 	for {
-		expr, e := p.expression()
-		if e == nil && expr != nil {
-			bb.expressions = append(bb.expressions, expr)
+		ep := p.span
+		stmt, e := p.statement()
+		if e == nil && stmt != nil {
+			p.declare(stmt)
+			bb.statements = append(bb.statements, stmt)
 		} else if e != nil {
-			return nil, e
+			p.report(e)
+			p.synchronize()
+			// the "]" of an invalid type doesn't close anything in the block
+			for p.tt == RBRACKET {
+				p.consume()
+				p.synchronize()
+			}
+			bb.statements = append(bb.statements, &BadStmt{p.spanFrom(ep)})
 		} else {
-			stmt, e := p.statement()
-			if e == nil && stmt != nil {
-				bb.statements = append(bb.statements, stmt)
+			expr, e := p.expression()
+			if e == nil && expr != nil {
+				p.declare(expr)
+				bb.expressions = append(bb.expressions, expr)
 			} else if e != nil {
-				return nil, e
+				p.report(e)
+				p.synchronize()
+				bb.expressions = append(bb.expressions, &BadExpr{p.spanFrom(ep)})
 			}
 		}
 
-		switch p.tt {
-		case COMMA:
-			p.consume()
-			continue
-		case RBRACE:
-			p.consume() // consume the RBRACE
-			fallthrough
-		case EOF:
-			return bb, nil
-		default:
-			return nil, p.syntaxError("expected \",\" or \"}\". Got \"" + p.data + "\"")
+		for separated := false; !separated; {
+			switch p.tt {
+			case COMMA, SEMICOLON:
+				p.consume()
+				separated = true
+			case closing:
+				p.consume() // consume the RBRACE
+				bb.span = p.spanFrom(start)
+				return bb
+			case EOF:
+				d := newDiagnostic(p.span, codeSyntax, "expected \"}\". Got \"EOF\"")
+				if closing == RBRACE {
+					d.withLabel(start, "the block starts here")
+				}
+				p.report(d)
+				bb.span = p.spanFrom(start)
+				return bb
+			default:
+				p.report(p.syntaxError("expected \",\" or \"}\". Got \"" + p.data + "\""))
+				bad := p.span
+				p.synchronize()
+				// a closing token that doesn't belong to this block is skipped
+				for p.tt == RBRACKET || p.tt == RBRACE && closing != RBRACE {
+					p.consume()
+					p.synchronize()
+				}
+				bb.expressions = append(bb.expressions, &BadExpr{p.spanFrom(bad)})
+			}
+		}
+	}
+}
+
+// declare adds the variables of a short declaration, a variable declaration, the name of a type
+// declaration or a variant and its cases to the scope of the current block, so the expressions
+// after it can resolve their type. `c: d: 1` declares both c and d.
+func (p *parser) declare(node interface{}) {
+	scope := p.scopes[len(p.scopes)-1]
+	switch n := node.(type) {
+	case *VariableDeclaration:
+		for _, v := range n.variables {
+			scope[v.name] = v
+		}
+		return
+	case *TypeDeclaration:
+		scope[n.variable.name] = n.variable
+		p.types[n.variable.varType.(*BocType)] = n
+		return
+	case *VariantDeclaration:
+		scope[n.variable.name] = n.variable
+		for _, c := range n.cases {
+			scope[c.name] = c
+		}
+		return
+	}
+	for sd, ok := node.(*ShortDeclaration); ok; sd, ok = sd.value.(*ShortDeclaration) {
+		scope[sd.variable.name] = sd.variable
+	}
+}
+
+// resolve sets the type of a variable reference to the type of the variable it refers to,
+// if it was declared before in the current block or in an enclosing one. It returns the
+// declared variable, or nil if there is none.
+func (p *parser) resolve(v *Variable) *Variable {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if declared, ok := p.scopes[i][v.name]; ok {
+			v.varType = declared.varType
+			return declared
+		}
+	}
+	return nil
+}
 
+// synchronize skips tokens until a "," or a closing "}" or "]" that is not nested in another
+// block, array or parenthesis is found, so the parser can resume after a syntax error.
+// Inside an argument list it also stops at the ")" that closes it.
+func (p *parser) synchronize() {
+	depth := 0
+	for p.tt != EOF {
+		switch p.tt {
+		case LBRACE, LBRACKET, LPAREN:
+			depth++
+		case RBRACE, RBRACKET:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case RPAREN:
+			if depth > 0 {
+				depth--
+			} else if p.parens > 0 {
+				return
+			}
+		case COMMA, SEMICOLON:
+			if depth == 0 {
+				return
+			}
 		}
+		p.consume()
 	}
-	//return bb, nil
+}
 
+// spanFrom returns a span from the start of the given span to the end of the last consumed token.
+func (p *parser) spanFrom(start span) span {
+	return span{start.file, start.start, max(p.prevEnd, start.start)}
+}
+
+// report records a syntax error and lets the parser continue.
+func (p *parser) report(e error) {
+	p.diagnostics.addError("", codeSyntax, e)
 }
 
 // expression ::= block_invocation
@@ -122,19 +265,58 @@ func (p *parser) boc() (*Boc, error) {
 //	| assignment
 //	| variable_short_definition
 func (p *parser) expression() (expression, error) {
+	if n := p.assignmentAhead(p.currentIndex); n > 0 {
+		return p.parseAssignment(n)
+	}
+	start := p.span
+	exp, err := p.operand()
+	if err != nil || exp == nil {
+		return exp, err
+	}
+	switch exp.(type) {
+	case *ShortDeclaration, *KeyValue:
+		// the value was already parsed as a full expression
+		return exp, nil
+	}
+	exp, err = p.postfix(start, exp)
+	if err != nil {
+		return nil, err
+	}
+	exp, err = p.nonParenthesisInvocations(start, exp)
+	if err != nil || p.tt != MATCH {
+		return exp, err
+	}
+	return p.parseMatch(start, exp)
+}
+
+// operand parses an expression that is not an invocation: a literal, a variable, a type, a short
+// declaration, a when or expressions between parenthesis.
+func (p *parser) operand() (expression, error) {
 	token := p.tt
 	switch token {
-	case INTEGER, DECIMAL, STRING, IDENTIFIER, NON_WORD_IDENTIFIER:
+	case INTEGER, DECIMAL, STRING, BOOLEAN, IDENTIFIER, NON_WORD_IDENTIFIER, GENERIC_TYPE_IDENTIFIER:
 		return p.parseLiteralOrShortDeclaration()
+	case STRING_HEAD:
+		return p.parseInterpolatedString()
 	case LBRACE:
 		p.consume() // consume the {
 		return p.parseBlockLiteral()
 	case RBRACE:
 		return nil, nil
 	case LBRACKET:
-		ap := p.pos
+		ap := p.span
 		p.consume()
 		return p.parseArrayOrDictionaryLiteral(ap)
+	case LPAREN:
+		return p.parseParenthesisExp()
+	case WHEN:
+		return p.parseWhen()
+	case TYPE_IDENTIFIER:
+		// a type used as a value, like a variant case that constructs a value: Some(1)
+		v := &Variable{p.span, p.data, newTBD()}
+		p.resolve(v)
+		p.consume()
+		return v, nil
 	case EOF:
 		return nil, nil
 	default:
@@ -142,11 +324,81 @@ func (p *parser) expression() (expression, error) {
 	}
 }
 
+// parseInterpolatedString parses a string literal with placeholders, the current position is at the
+// literal text before the first one. Each placeholder has an expression, a block in it has to be
+// invoked to have a value: "`items.size()` items". An invalid placeholder is reported and skipped.
+//
+// interpolated_string ::= string_head expression (string_middle expression)* string_tail
+func (p *parser) parseInterpolatedString() (expression, error) {
+	start := p.span
+	is := &InterpolatedString{span{}, []string{p.data}, []expression{}}
+	p.consume() // consume the STRING_HEAD
+	for {
+		if p.tt == STRING_MIDDLE || p.tt == STRING_TAIL {
+			// an empty placeholder, reported by the tokenizer
+			is.values = append(is.values, &BadExpr{p.span})
+		} else {
+			is.values = append(is.values, p.placeholder())
+		}
+		if p.tt != STRING_MIDDLE && p.tt != STRING_TAIL {
+			return nil, p.syntaxError("expected the end of the string. Got \"" + p.data + "\"")
+		}
+		is.parts = append(is.parts, p.data)
+		tail := p.tt == STRING_TAIL
+		p.consume()
+		if tail {
+			break
+		}
+	}
+	is.span = p.spanFrom(start)
+	return is, nil
+}
+
+// placeholder parses the expression of a placeholder, until the literal text after it. An invalid
+// expression is reported, skipped and returned as a BadExpr.
+func (p *parser) placeholder() expression {
+	start := p.span
+	value, err := p.expression()
+	if err == nil && value == nil {
+		err = p.syntaxError("expected an expression in the placeholder. Got \"" + p.data + "\"")
+	}
+	if err == nil && p.tt != STRING_MIDDLE && p.tt != STRING_TAIL {
+		err = p.syntaxError("expected \"`\" after the expression of the placeholder. Got \"" + p.data + "\"")
+	}
+	if err != nil {
+		p.report(err)
+		for p.tt != STRING_MIDDLE && p.tt != STRING_TAIL && p.tt != EOF {
+			p.consume()
+		}
+		return &BadExpr{p.spanFrom(start)}
+	}
+	p.interpolated(value)
+	return value
+}
+
+// interpolated reports the value of a placeholder that can't be written in a string: a block that is
+// not invoked or a tuple.
+func (p *parser) interpolated(value expression) {
+	switch t := value.dataType().(type) {
+	case *BocType:
+		if t.name == "" {
+			hint := "invoke it to write its result"
+			if v, ok := value.(*Variable); ok {
+				hint += ", like `" + v.name + "()`"
+			}
+			p.report(newDiagnostic(spanOf(value), codeType, "cannot write a block in a string").withHint(hint))
+		}
+	case *TupleType:
+		p.report(newDiagnostic(spanOf(value), codeType, "cannot write a tuple in a string").
+			withHint("write each value in its own placeholder"))
+	}
+}
+
 // a, 1, "hello", 1.0
 // a: 1, b: "hello", c: 1.0
 func (p *parser) parseLiteralOrShortDeclaration() (expression, error) {
 	token := p.tt
-	ctp := p.pos
+	ctp := p.span
 	ctd := p.data
 
 	p.consume() // consume the  literal that brought us here
@@ -154,7 +406,7 @@ func (p *parser) parseLiteralOrShortDeclaration() (expression, error) {
 	var variable *Variable
 	var basicLit *BasicLit
 	basicType := typeFromTokenType(token)
-	if token == IDENTIFIER || token == NON_WORD_IDENTIFIER {
+	if isVariableName(token) || token == NON_WORD_IDENTIFIER {
 		variable = &Variable{ctp, ctd, basicType}
 		exp = variable
 	} else {
@@ -168,13 +420,19 @@ func (p *parser) parseLiteralOrShortDeclaration() (expression, error) {
 		if err != nil {
 			return nil, err
 		}
+		if val == nil {
+			return nil, p.syntaxError("expected an expression after \":\". Got \"" + p.data + "\"")
+		}
 		if _, ok := basicType.(*TBD); ok {
 			variable.varType = val.dataType()
-			return &ShortDeclaration{ctp, variable, val}, nil
+			return &ShortDeclaration{p.spanFrom(ctp), variable, val}, nil
 		} else {
-			return &KeyValue{ctp, basicLit, val}, nil
+			return &KeyValue{p.spanFrom(ctp), basicLit, val}, nil
 		}
 	}
+	if variable != nil {
+		p.resolve(variable)
+	}
 	return exp, nil
 }
 
@@ -186,6 +444,8 @@ func typeFromTokenType(token tokenType) Type {
 		return new(DecimalType)
 	case STRING:
 		return new(StringType)
+	case BOOLEAN:
+		return new(BoolType)
 	default:
 		return new(TBD)
 	}
@@ -193,38 +453,28 @@ func typeFromTokenType(token tokenType) Type {
 }
 
 func (p *parser) parseBlockLiteral() (expression, error) {
-	return p.boc()
+	return p.boc(RBRACE), nil
 }
 
-func (p *parser) parseArrayOrDictionaryLiteral(ap position) (expression, error) {
+func (p *parser) parseArrayOrDictionaryLiteral(ap span) (expression, error) {
 	if p.tt == RBRACKET {
 		p.consume()
 		return p.parseTypedArrayLiteral(ap)
-	} else if p.tt == TYPE_IDENTIFIER {
+	} else if p.tt == TYPE_IDENTIFIER && p.currentIndex+1 < len(p.tokens) && p.tokens[p.currentIndex+1].tt == RBRACKET {
 		return p.parseEmptyDictionaryLiteral(ap)
 	} else {
 		return p.parseNonEmptyArrayOrDictionaryLiteral(ap)
 	}
 }
 
-// e.g [] Int, current position is at the TYPE_IDENTIFIER
-func (p *parser) parseTypedArrayLiteral(ap position) (expression, error) {
-	err := p.expect(TYPE_IDENTIFIER)
+// parseTypedArrayLiteral parses an empty array literal, the current position is at the type of its
+// elements, which can be any type: []Int, [][]Int, [][String:Int] or []#(Int, Int).
+func (p *parser) parseTypedArrayLiteral(ap span) (expression, error) {
+	elemType, err := p.parseType()
 	if err != nil {
 		return nil, err
 	}
-	ct := p.tt
-	//ctp := p.pos
-	ctd := p.data
-	p.consume()
-	at := new(ArrayType)
-	// TODO: need to handle arrays and dictionaries e.g. [][Int], [][String:Int]
-	elemType := typeFromTokenType(ct)
-	if ct == TYPE_IDENTIFIER {
-		elemType = typeFromTokenData(ctd)
-	}
-	at.elemType = elemType
-	return &ArrayLit{ap, []expression{}, at}, nil
+	return &ArrayLit{p.spanFrom(ap), []expression{}, &ArrayType{elemType: elemType}}, nil
 }
 
 func typeFromTokenData(tokenData string) Type {
@@ -235,84 +485,151 @@ func typeFromTokenData(tokenData string) Type {
 		return new(DecimalType)
 	case "String":
 		return new(StringType)
+	case "Bool":
+		return new(BoolType)
 	default:
 
 		return new(TBD)
 	}
 }
 
-// [ String ] Int
-func (p *parser) parseEmptyDictionaryLiteral(ap position) (expression, error) {
+// parseEmptyDictionaryLiteral parses an empty dictionary literal, the current position is at the key
+// type. The value can be any type: [String]Int, [String][]Int or [String][String:Int].
+func (p *parser) parseEmptyDictionaryLiteral(ap span) (expression, error) {
 	dictType := new(DictType)
-	dictType.keyType = typeFromTokenData(p.data)
+	dictType.keyType = p.knownType(p.data)
+	p.checkKeyType(p.span, dictType.keyType)
 	p.consume()
-	if err := p.expect(RBRACKET); err != nil {
-		return nil, err
-	}
 	p.consume() // consume the RBRACKET
-	if err := p.expect(TYPE_IDENTIFIER); err != nil {
+	valType, err := p.parseType()
+	if err != nil {
 		return nil, err
 	}
-	dictType.valType = typeFromTokenData(p.data)
-	p.consume()
-	return &DictLit{ap, dictType, []expression{}, []expression{}}, nil
+	dictType.valType = valType
+	return &DictLit{p.spanFrom(ap), dictType, []expression{}, []expression{}}, nil
 }
 
 // [ (expression (, )?)+ ]
 // [ (expression : expression (, )?)+ ]
-func (p *parser) parseNonEmptyArrayOrDictionaryLiteral(ap position) (expression, error) {
+// Invalid elements are reported and replaced by BadExpr nodes and the parsing continues after the next "," or "]".
+func (p *parser) parseNonEmptyArrayOrDictionaryLiteral(ap span) (expression, error) {
+	p.lists++
+	defer func() { p.lists-- }()
 	var exps []expression
 	dl := &DictLit{ap, newDictType(), []expression{}, []expression{}}
 	insideDict := false
+	literal := func() (expression, error) {
+		if insideDict {
+			dl.span = p.spanFrom(ap)
+			if t := p.elementType(dl.keys, "keys of the dictionary"); t != nil {
+				dl.dictType.keyType = t
+			}
+			if t := p.elementType(dl.values, "values of the dictionary"); t != nil {
+				dl.dictType.valType = t
+			}
+			return dl, nil
+		}
+		p.elementType(exps, "elements of the array")
+		return createArrayLiteral(p.spanFrom(ap), exps)
+	}
 
 	for {
+		ep := p.span
 		expr, err := p.expression()
+		if err == nil && expr == nil {
+			err = p.syntaxError("expected an expression. Got \"" + p.data + "\"")
+		}
 		if err != nil {
-			return nil, err
+			p.report(err)
+			p.synchronize()
+			expr = &BadExpr{p.spanFrom(ep)}
 		}
 
 		if kv, ok := expr.(*KeyValue); ok {
 			insideDict = true
 			dl.keys = append(dl.keys, kv.key)
 			dl.values = append(dl.values, kv.val)
-			dl.dictType.keyType = kv.key.dataType()
-			dl.dictType.valType = kv.val.dataType()
 		} else if sd, ok := expr.(*ShortDeclaration); ok {
 			insideDict = true
 			dl.keys = append(dl.keys, sd.variable)
 			dl.values = append(dl.values, sd.value)
-			dl.dictType.keyType = sd.variable.dataType()
-			dl.dictType.valType = sd.value.dataType()
-		} else if expr != nil {
-			exps = append(exps, expr)
+		} else if _, ok := expr.(*BadExpr); ok && insideDict {
+			dl.keys = append(dl.keys, expr)
+			dl.values = append(dl.values, expr)
 		} else {
-			return nil, err
+			exps = append(exps, expr)
 		}
 
-		ct := p.tt
-		p.consume()
-
-		if ct == COMMA {
-			ct = p.tt
-			if ct == RBRACKET {
-				p.consume() // consume the RBRACKET
+		for separated := false; !separated; {
+			switch p.tt {
+			case COMMA:
+				p.consume()
+				if p.tt == RBRACKET {
+					p.consume() // consume the RBRACKET
+					return literal()
+				}
+				separated = true
+			case RBRACKET:
+				p.consume()
+				return literal()
+			case RBRACE, EOF:
+				// the enclosing block is closed, report the missing "]" and let the block finish
+				p.report(newDiagnostic(p.span, codeSyntax, "expected \",\" or \"]\". Got \""+p.data+"\"").
+					withLabel(ap, "the literal starts here"))
+				return literal()
+			default:
+				p.report(p.syntaxError("expected \",\" or \"]\". Got \"" + p.data + "\""))
+				bad := p.span
+				p.synchronize()
+				// the element skipped keeps its place in the literal
 				if insideDict {
-					return dl, nil
+					dl.keys = append(dl.keys, &BadExpr{p.spanFrom(bad)})
+					dl.values = append(dl.values, &BadExpr{p.spanFrom(bad)})
+				} else {
+					exps = append(exps, &BadExpr{p.spanFrom(bad)})
+				}
+				switch p.tt {
+				case RPAREN:
+					// the enclosing argument list is closed
+					return literal()
+				case SEMICOLON:
+					// reported as the wrong separator, the elements after it are still part of the literal
+					p.consume()
+					if p.tt == RBRACKET {
+						p.consume() // consume the RBRACKET
+						return literal()
+					}
+					separated = true
 				}
-				return createArrayLiteral(ap, exps)
 			}
-			continue
 		}
+	}
+}
 
-		if ct == RBRACKET {
-			if insideDict {
-				return dl, nil
-			}
-			return createArrayLiteral(ap, exps)
+// elementType returns the type of the first element of a literal whose type is known, or nil if there
+// is none, and reports the elements of another type, like the "x" of [1, "x"].
+func (p *parser) elementType(elements []expression, what string) Type {
+	var first expression
+	for _, e := range elements {
+		t := e.dataType()
+		if isTBD(t) {
+			continue
 		}
-
-		return nil, p.syntaxError("expected \",\" or \"]\". Got " + p.data)
+		if first == nil {
+			first = e
+			continue
+		}
+		ft := first.dataType()
+		if !assignable(ft, t) || !assignable(t, ft) {
+			p.report(newDiagnostic(spanOf(e), codeType, fmt.Sprintf("the %s have different types: %s and %s", what, ft, t)).
+				withLabel(spanOf(first), "the first one is "+ft.String()).
+				withHint("write values of the same type"))
+		}
+	}
+	if first == nil {
+		return nil
 	}
+	return first.dataType()
 }
 
 func newDictType() *DictType {
@@ -332,7 +649,7 @@ func newTBD() *TBD {
 	return new(TBD)
 }
 
-func createArrayLiteral(ap position, exps []expression) (expression, error) {
+func createArrayLiteral(ap span, exps []expression) (expression, error) {
 	switch exps[0].(type) {
 	case *ArrayLit:
 		al, _ := exps[0].(*ArrayLit)
@@ -351,23 +668,1402 @@ func createArrayLiteral(ap position, exps []expression) (expression, error) {
 
 	}
 }
-func (p *parser) statement() (statement, error) {
-	return nil, nil // fmt.Errorf("not implemented")
+
+// postfix parses the member accesses and argument lists that follow an operand, e.g. foo.bar(1)().
+// start is the span of the first token of the operand.
+//
+// block_invocation ::= expression invocation
+// method_invocation ::= member_access invocation
+// member_access ::= expression ("." variable)+
+func (p *parser) postfix(start span, exp expression) (expression, error) {
+	for {
+		switch p.tt {
+		case LPAREN:
+			inv := &Invocation{span{}, exp, []expression{}, []*NamedArg{}}
+			p.arguments(inv)
+			inv.span = p.spanFrom(start)
+			exp = p.instantiation(inv)
+		case PERIOD:
+			p.consume() // consume the PERIOD
+			if p.tt != IDENTIFIER && p.tt != NON_WORD_IDENTIFIER {
+				return nil, p.syntaxError("expected a member name after \".\". Got \"" + p.data + "\"")
+			}
+			member := &Variable{p.span, p.data, newTBD()}
+			p.consume()
+			exp = p.newMemberAccess(p.spanFrom(start), exp, member)
+		case LBRACKET:
+			return p.parseIndex(start, exp)
+		default:
+			return exp, nil
+		}
+	}
+}
+
+// parseIndex parses the read or the write of an element of the receiver, the current position is
+// at the "[". The members and the elements of the element read can be accessed after it: a[0].b, a[0][1].
+//
+// array_read ::= expression "[" expression "]"
+// array_write ::= expression "[" expression "]" "=" expression
+// dictionary_write ::= expression "[" expression ":" expression "]"
+func (p *parser) parseIndex(start span, receiver expression) (expression, error) {
+	p.consume() // consume the LBRACKET
+	// the "," and ")" in the index don't belong to the lists around it
+	parens, lists := p.parens, p.lists
+	p.parens, p.lists = 0, 0
+	index, err := p.expression()
+	p.parens, p.lists = parens, lists
+	if err != nil {
+		return nil, err
+	}
+	if index == nil {
+		err := p.syntaxError("expected an index after \"[\". Got \"" + p.data + "\"")
+		if p.tt == RBRACKET {
+			p.consume() // the "]" of the missing index doesn't close anything else
+		}
+		return nil, err
+	}
+	// d["key": "value"] writes the value of the key
+	var value expression
+	switch kv := index.(type) {
+	case *KeyValue:
+		index, value = kv.key, kv.val
+	case *ShortDeclaration:
+		// the key is a variable declared before, not a new one
+		key := &Variable{kv.variable.span, kv.variable.name, newTBD()}
+		p.resolve(key)
+		index, value = key, kv.value
+	}
+	if err := p.expect(RBRACKET); err != nil {
+		return nil, err
+	}
+	p.consume() // consume the RBRACKET
+	i := &Index{p.spanFrom(start), receiver, index, p.indexType(receiver, index)}
+	if value == nil && p.tt == ASSIGN {
+		p.consume() // consume the ASSIGN
+		if value, err = p.expression(); err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, p.syntaxError("expected an expression after \"=\". Got \"" + p.data + "\"")
+		}
+	}
+	if value == nil {
+		return p.postfix(start, i)
+	}
+	p.checkElementValue(i, value)
+	return &IndexAssignment{p.spanFrom(start), i, value}, nil
+}
+
+// indexType returns the type of the element read by the index of the receiver, the index of an
+// array is an Int and the one of a dictionary its key. A dictionary read is an Option of its value.
+// An invalid index is reported.
+func (p *parser) indexType(receiver, index expression) Type {
+	t := index.dataType()
+	switch rt := receiver.dataType().(type) {
+	case *ArrayType:
+		if !assignable(new(IntType), t) {
+			p.report(newDiagnostic(spanOf(index), codeType, "expected an Int index. Got "+t.String()).
+				withLabel(spanOf(receiver), "this is "+rt.String()))
+		}
+		return rt.elemType
+	case *DictType:
+		if !assignable(rt.keyType, t) {
+			p.report(newDiagnostic(spanOf(index), codeType, fmt.Sprintf("expected a %s key. Got %s", rt.keyType, t)).
+				withLabel(spanOf(receiver), "this is "+rt.String()))
+		}
+		return optionType(rt.valType)
+	case *TBD:
+		return newTBD()
+	default:
+		p.report(newDiagnostic(spanOf(receiver), codeType, "cannot index a value of type "+rt.String()).
+			withHint("only the arrays and the dictionaries have elements"))
+		return newTBD()
+	}
+}
+
+// checkElementValue reports a value that can't be an element of the array or the dictionary it's written to.
+func (p *parser) checkElementValue(i *Index, value expression) {
+	var elemType Type
+	switch rt := i.receiver.dataType().(type) {
+	case *ArrayType:
+		elemType = rt.elemType
+	case *DictType:
+		elemType = rt.valType
+	default:
+		return
+	}
+	if t := value.dataType(); !assignable(elemType, t) {
+		p.report(newDiagnostic(spanOf(value), codeType,
+			fmt.Sprintf("cannot use %s value as %s in the element of %s", t, elemType, i.receiver.stringValue())).
+			withLabel(spanOf(i.receiver), "this is "+i.receiver.dataType().String()))
+	}
+}
+
+// instantiation returns the creation of a value of a user-defined type or of a case of a variant if
+// the callee of the invocation is the type, otherwise it returns the invocation. The arguments are
+// the members of the type, except its methods, in order or by name. The members not passed get the
+// value they have in the type declaration, the ones without a value must be passed. The type
+// arguments of a generic type are inferred from the values of the members.
+//
+// type_instantiation ::= type_identifier parenthesis_invocation
+func (p *parser) instantiation(inv *Invocation) expression {
+	v, ok := inv.callee.(*Variable)
+	if !ok {
+		return inv
+	}
+	bt, ok := v.varType.(*BocType)
+	if !ok || bt.name == "" || bt.name != v.name || len(inv.args) > 0 && len(inv.namedArgs) > 0 {
+		// mixing positional and named arguments is already reported
+		return inv
+	}
+	defaults := p.defaultValues(bt)
+	var members []*Variable
+	isMember := map[string]bool{}
+	for _, m := range bt.variables {
+		if _, method := defaults[m.name].(*Boc); m.name != "" && !method {
+			members = append(members, m)
+			isMember[m.name] = true
+		}
+	}
+	ti := &TypeInstantiation{inv.span, v, bt, []*NamedArg{}, []*NamedArg{}}
+	passed := map[string]*NamedArg{}
+	for i, a := range inv.args {
+		if i >= len(members) {
+			p.report(newDiagnostic(spanOf(a), codeType, fmt.Sprintf("too many arguments for %s, it has %d members", bt.name, len(members))))
+			break
+		}
+		passed[members[i].name] = &NamedArg{spanOf(a), members[i].name, a}
+	}
+	for _, na := range inv.namedArgs {
+		switch {
+		case passed[na.name] != nil:
+			p.report(newDiagnostic(na.span, codeType, "duplicate argument \""+na.name+"\"").
+				withLabel(passed[na.name].span, "first passed here"))
+		case !isMember[na.name]:
+			p.report(newDiagnostic(na.span, codeType, fmt.Sprintf("%s has no member \"%s\"", bt.name, na.name)))
+		default:
+			passed[na.name] = na
+		}
+	}
+	var missing []string
+	for _, m := range members {
+		if arg, ok := passed[m.name]; ok {
+			ti.values = append(ti.values, arg)
+		} else if d, ok := defaults[m.name]; ok {
+			ti.defaults = append(ti.defaults, &NamedArg{spanOf(d), m.name, d})
+		} else {
+			missing = append(missing, m.name)
+		}
+	}
+	if params := typeParamsOf(bt); len(params) > 0 {
+		// the type arguments are the types of the values of the members: Some(1) is an Option(Int)
+		b := newBindings(params)
+		for _, na := range append(ti.values, ti.defaults...) {
+			b.infer(memberType(bt, na.name), na.value.dataType())
+		}
+		ti.instType = b.resolve().substitute(bt).(*BocType)
+		// the values whose type arguments can't be inferred take them from the member: Cons(1, Empty())
+		for _, na := range ti.values {
+			if inner, ok := na.value.(*TypeInstantiation); ok {
+				inner.complete(memberType(ti.instType, na.name))
+			}
+		}
+	}
+	for _, m := range members {
+		arg, ok := passed[m.name]
+		if !ok {
+			continue
+		}
+		if mt, t := memberType(ti.instType, m.name), arg.value.dataType(); !assignable(mt, t) {
+			p.report(newDiagnostic(spanOf(arg.value), codeType,
+				fmt.Sprintf("cannot use %s value as %s in the member %s of %s", t, mt, m.name, bt.name)).
+				withLabel(m.span, "declared as "+m.varType.String()+" here"))
+		}
+	}
+	if len(missing) > 0 {
+		p.report(newDiagnostic(inv.span, codeType, fmt.Sprintf("missing the value of %s in %s", strings.Join(missing, ", "), bt.name)).
+			withHint("pass a value for each member without a default value"))
+	}
+	return ti
+}
+
+// typeParamsOf returns the type parameters of a user-defined type, or of the variant of a case.
+func typeParamsOf(bt *BocType) []*GenericType {
+	if vt, ok := bt.result.(*VariantType); ok && isCaseOf(bt, vt) {
+		return vt.typeParams
+	}
+	return bt.typeParams
+}
+
+// memberType returns the type of the member of a user-defined type with the name.
+func memberType(bt *BocType, name string) Type {
+	for _, v := range bt.variables {
+		if v.name == name {
+			return v.varType
+		}
+	}
+	return newTBD()
+}
+
+// defaultValues returns the values of the variables declared in the body of a user-defined type,
+// by name. The members of the signature and the cases of variants have no default value.
+func (p *parser) defaultValues(bt *BocType) map[string]expression {
+	values := map[string]expression{}
+	td, ok := p.types[bt]
+	if !ok || td.body == nil {
+		return values
+	}
+	for _, e := range td.body.expressions {
+		if sd, ok := e.(*ShortDeclaration); ok {
+			for ; ok; sd, ok = sd.value.(*ShortDeclaration) {
+				values[sd.variable.name] = sd.value
+			}
+		}
+	}
+	for _, s := range td.body.statements {
+		if vd, ok := s.(*VariableDeclaration); ok && len(vd.values) == len(vd.variables) {
+			for i, v := range vd.variables {
+				values[v.name] = vd.values[i]
+			}
+		}
+	}
+	return values
+}
+
+// newMemberAccess creates the access to a member of the receiver. If the receiver is a boc with a
+// variable of the same name or a basic type with an operator of that name the member gets its type,
+// a boc without it reports the unknown member, otherwise it's TBD.
+func (p *parser) newMemberAccess(span span, receiver expression, member *Variable) *MemberAccess {
+	if bt := operatorType(receiver.dataType(), member.name); bt != nil {
+		member.varType = bt
+	} else if bocType, ok := receiver.dataType().(*BocType); ok {
+		found := false
+		for _, v := range bocType.variables {
+			if v.name == member.name {
+				member.varType = v.varType
+				found = true
+				break
+			}
+		}
+		if !found {
+			p.report(newDiagnostic(member.span, codeType, fmt.Sprintf("%s has no member \"%s\"", bocType, member.name)))
+		}
+	}
+	return &MemberAccess{span, receiver, member}
+}
+
+// nonParenthesisInvocations parses the non-word identifiers that follow the receiver as invocations
+// of its methods: `a + 1` is `a.+(1)`.
+//
+// All the non-word identifiers have the same precedence and are left associative, so `a + b * c`
+// is `(a + b) * c`. Parenthesis invocations bind tighter: `a + f(1)` is `a.+(f(1))`.
+// A "," followed by another expression passes one more argument to the last invocation:
+// `n > 0 ? {1}, {2}` is `n.>(0).?({1}, {2})`. This only happens at the block level and stops at a
+// line break or a short declaration, inside an argument list or an array the "," separates its elements.
+//
+// non_parenthesis_invocation ::= non_word_identifier expression ("," expression)*
+func (p *parser) nonParenthesisInvocations(start span, receiver expression) (expression, error) {
+	for p.tt == NON_WORD_IDENTIFIER || p.tt == EQUALS {
+		method := &Variable{p.span, p.data, newTBD()}
+		p.consume()
+		callee := p.newMemberAccess(p.spanFrom(start), receiver, method)
+		if p.tt == COMMA && p.data == "\n" {
+			// the expression continues in the next line
+			p.consume()
+		}
+		arg, err := p.nonParenthesisArgument(method.name)
+		if err != nil {
+			return nil, err
+		}
+		inv := &Invocation{span{}, callee, []expression{arg}, []*NamedArg{}}
+		for p.parens == 0 && p.lists == 0 && p.tt == COMMA && p.data == "," && !p.shortDeclarationAhead() {
+			p.consume() // consume the COMMA
+			arg, err = p.nonParenthesisArgument(",")
+			if err != nil {
+				return nil, err
+			}
+			inv.args = append(inv.args, arg)
+		}
+		inv.span = p.spanFrom(start)
+		receiver = inv
+	}
+	return receiver, nil
+}
+
+// nonParenthesisArgument parses an argument of a non-parenthesis invocation: an operand with its
+// member accesses and parenthesis invocations. after is the token before the argument, used in the error message.
+func (p *parser) nonParenthesisArgument(after string) (expression, error) {
+	start := p.span
+	arg, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if arg == nil {
+		return nil, p.syntaxError("expected an expression after \"" + after + "\". Got \"" + p.data + "\"")
+	}
+	return p.postfix(start, arg)
+}
+
+// parseWhen parses a when expression, the current position is at the "when". The cases are blocks
+// with a condition and a body separated by "=>", the default case has the condition "_". An invalid
+// case is reported and skipped.
+//
+// when ::= "when" when_case (("," | "\n") when_case)*
+// when_case ::= "{" (expression | "_") "=>" block_body "}"
+func (p *parser) parseWhen() (expression, error) {
+	start := p.span
+	p.consume() // consume the WHEN
+	w := &When{span{}, []*WhenCase{}, nil}
+	if !p.caseAhead(p.currentIndex) {
+		return nil, p.syntaxError("expected a case after \"when\" like { condition => value }. Got \"" + p.data + "\"")
+	}
+	for {
+		if err := p.whenCase(w); err != nil {
+			p.report(err)
+			p.skipBlock()
+			if p.tt == RBRACE {
+				p.consume()
+			}
+		}
+		// the cases are separated by "," or a line break
+		next := p.currentIndex
+		for next < len(p.tokens) && p.tokens[next].tt == COMMA {
+			next++
+		}
+		if !p.caseAhead(next) {
+			break
+		}
+		for p.tt == COMMA {
+			p.consume()
+		}
+	}
+	w.span = p.spanFrom(start)
+	p.checkWhen(w)
+	return w, nil
+}
+
+// caseAhead returns true if the token i opens a block with a "=>" in it, like { a > 0 => 1 }.
+func (p *parser) caseAhead(i int) bool {
+	if i >= len(p.tokens) || p.tokens[i].tt != LBRACE {
+		return false
+	}
+	for depth := 0; i < len(p.tokens); i++ {
+		switch p.tokens[i].tt {
+		case LBRACE, LBRACKET, LPAREN:
+			depth++
+		case RBRACE, RBRACKET, RPAREN:
+			depth--
+			if depth == 0 {
+				return false
+			}
+		case THEN_ARROW:
+			if depth == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// whenCase parses a case of a when and adds it to it, the current position is at the "{".
+func (p *parser) whenCase(w *When) error {
+	start := p.span
+	p.consume() // consume the LBRACE
+	// the condition is not an element of the lists around the when
+	parens, lists := p.parens, p.lists
+	p.parens, p.lists = 0, 0
+	defer func() { p.parens, p.lists = parens, lists }()
+	c := &WhenCase{}
+	if p.tt == NON_WORD_IDENTIFIER && p.data == "_" {
+		p.consume() // consume the default case
+	} else {
+		condition, err := p.expression()
+		if err != nil {
+			return err
+		}
+		if condition == nil {
+			return p.syntaxError("expected a condition or \"_\". Got \"" + p.data + "\"")
+		}
+		if t := condition.dataType(); !assignable(new(BoolType), t) {
+			p.report(newDiagnostic(spanOf(condition), codeType, "expected a Bool condition. Got "+t.String()))
+		}
+		c.condition = condition
+	}
+	if p.tt != THEN_ARROW {
+		return p.syntaxError("expected \"=>\" after the condition. Got \"" + p.data + "\"")
+	}
+	p.consume() // consume the THEN_ARROW
+	c.body = p.boc(RBRACE)
+	c.span = p.spanFrom(start)
+	w.cases = append(w.cases, c)
+	return nil
+}
+
+// checkWhen reports the cases after the default case, which are never evaluated, and sets the type
+// of the when to the type shared by the bodies of its cases.
+func (p *parser) checkWhen(w *When) {
+	for i, c := range w.cases {
+		if c.condition == nil && i < len(w.cases)-1 {
+			p.report(newDiagnostic(w.cases[i+1].span, codeType, "unreachable case after the default case").
+				withLabel(c.span, "the default case"))
+			break
+		}
+	}
+	bodies := make([]*Boc, len(w.cases))
+	for i, c := range w.cases {
+		bodies[i] = c.body
+	}
+	w.whenType = p.casesType("when", bodies)
+}
+
+// casesType returns the type shared by the bodies of the cases of a when or a match, or TBD if
+// they have different types, which is reported. Bodies with a TBD value match any type.
+func (p *parser) casesType(construct string, bodies []*Boc) Type {
+	var first *Boc
+	var casesType Type = newTBD()
+	for _, body := range bodies {
+		t := body.dataType().(*BocType).result
+		if t == nil || isTBD(t) {
+			continue
+		}
+		if first == nil {
+			first, casesType = body, t
+			continue
+		}
+		if !assignable(casesType, t) || !assignable(t, casesType) {
+			p.report(newDiagnostic(body.span, codeType,
+				fmt.Sprintf("the cases of %s have different types: %s and %s", construct, casesType, t)).
+				withLabel(first.span, "this case is "+casesType.String()))
+			return newTBD()
+		}
+	}
+	return casesType
+}
+
+// caseType returns the type of the case of the variant of the value with the given name, or the
+// type with that name if the value is not a variant or has no such case.
+func (p *parser) caseType(value expression, name string) Type {
+	if vt, ok := value.dataType().(*VariantType); ok {
+		for _, vc := range vt.cases {
+			if vc.name == name {
+				return vc
+			}
+		}
+	}
+	return p.namedType(name)
+}
+
+// parseMatch parses the cases of a match of the value, the current position is at the "match".
+// The cases are blocks with a type name and a body separated by "=>", the default case has the
+// type "_". Inside the body of a case a variable matched has the type of the case, so the members
+// of a variant case can be used: opt match { Some => opt.value }. An invalid case is reported and skipped.
+//
+// match ::= expression "match" match_case (("," | "\n") match_case)*
+// match_case ::= "{" (type_identifier | "_") "=>" block_body "}"
+func (p *parser) parseMatch(start span, value expression) (expression, error) {
+	p.consume() // consume the MATCH
+	m := &Match{span{}, value, []*MatchCase{}, nil}
+	if !p.caseAhead(p.currentIndex) {
+		return nil, p.syntaxError("expected a case after \"match\" like { Type => value }. Got \"" + p.data + "\"")
+	}
+	for {
+		if err := p.matchCase(m); err != nil {
+			p.report(err)
+			p.skipBlock()
+			if p.tt == RBRACE {
+				p.consume()
+			}
+		}
+		next := p.currentIndex
+		for next < len(p.tokens) && p.tokens[next].tt == COMMA {
+			next++
+		}
+		if !p.caseAhead(next) {
+			break
+		}
+		for p.tt == COMMA {
+			p.consume()
+		}
+	}
+	m.span = p.spanFrom(start)
+	bodies := make([]*Boc, len(m.cases))
+	for i, c := range m.cases {
+		bodies[i] = c.body
+	}
+	m.matchType = p.casesType("match", bodies)
+	return m, nil
+}
+
+// matchCase parses a case of a match and adds it to it, the current position is at the "{".
+func (p *parser) matchCase(m *Match) error {
+	start := p.span
+	p.consume() // consume the LBRACE
+	c := &MatchCase{}
+	switch {
+	case p.tt == NON_WORD_IDENTIFIER && p.data == "_":
+		p.consume() // consume the default case
+	case p.tt == TYPE_IDENTIFIER:
+		c.pattern = &Variable{p.span, p.data, p.caseType(m.value, p.data)}
+		p.consume()
+	default:
+		return p.syntaxError("expected a type or \"_\". Got \"" + p.data + "\"")
+	}
+	if p.tt != THEN_ARROW {
+		return p.syntaxError("expected \"=>\" after the type. Got \"" + p.data + "\"")
+	}
+	p.consume() // consume the THEN_ARROW
+	// the variable matched has the type of the case in the body
+	v, narrowed := m.value.(*Variable)
+	narrowed = narrowed && c.pattern != nil && !isTBD(c.pattern.varType)
+	if narrowed {
+		p.scopes = append(p.scopes, map[string]*Variable{v.name: {v.span, v.name, c.pattern.varType}})
+	}
+	c.body = p.boc(RBRACE)
+	if narrowed {
+		p.scopes = p.scopes[:len(p.scopes)-1]
+	}
+	c.span = p.spanFrom(start)
+	m.cases = append(m.cases, c)
+	return nil
+}
+
+// assignmentAhead returns the number of variables of the assignment that starts at the token i, or 0
+// if the tokens aren't variables separated by "," followed by "=". Inside a list only single
+// assignments are recognized, the "," separates the elements of the list.
+func (p *parser) assignmentAhead(i int) int {
+	for n := 1; i+1 < len(p.tokens); i, n = i+2, n+1 {
+		if !isVariableName(p.tokens[i].tt) {
+			return 0
+		}
+		switch next := p.tokens[i+1]; {
+		case next.tt == ASSIGN:
+			return n
+		case next.tt == COMMA && next.data == "," && p.parens == 0 && p.lists == 0:
+			continue
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// parseAssignment parses the assignment of n variables, the current position is at the first variable.
+// All the values are evaluated before any variable is assigned, so `a, b = b, a` swaps them.
+// A single value can be assigned to several variables if it's a tuple of the same size.
+// Two assignments separated by "," like `a = 1, b = 2` are reported, they must be written as
+// `a, b = 1, 2` or be separated by ";" or a line break.
+//
+// assignment ::= variable ("," variable)* "=" expression ("," expression)*
+func (p *parser) parseAssignment(n int) (expression, error) {
+	start := p.span
+	as := &Assignment{span{}, make([]*Variable, 0, n), []expression{}}
+	declared := make([]*Variable, 0, n)
+	for len(as.variables) < n {
+		v := &Variable{p.span, p.data, newTBD()}
+		d := p.resolve(v)
+		if d == nil {
+			p.report(newDiagnostic(v.span, codeType, "undeclared variable "+v.name).
+				withHint("declare it before assigning it, like " + v.name + ": value"))
+			d = v
+		}
+		as.variables = append(as.variables, v)
+		declared = append(declared, d)
+		p.consume() // consume the variable
+		p.consume() // consume the COMMA or the ASSIGN
+	}
+	values, err := p.values(n)
+	if err != nil {
+		return nil, err
+	}
+	as.values = values
+	as.span = p.spanFrom(start)
+
+	if p.tt == COMMA && p.data == "," && p.assignmentAhead(p.currentIndex+1) > 0 {
+		next := p.tokens[p.currentIndex+1]
+		p.report(newDiagnostic(next.span, codeSyntax, "expected \";\" or a line break between assignments. Got \",\"").
+			withLabel(as.span, "the previous assignment").
+			withHint("assign several variables at once with a, b = 1, 2"))
+	}
+	p.checkValueCount(as.span, as.variables, as.values)
+	p.checkValueTypes("assignment", declared, as.values)
+	return as, nil
+}
+
+// values parses the values assigned to n variables, the current position is after the "=".
+// With several variables the values are separated by "," until the end of the line, another
+// assignment or a declaration.
+func (p *parser) values(n int) ([]expression, error) {
+	if n > 1 {
+		p.lists++
+		defer func() { p.lists-- }()
+	}
+	var values []expression
+	for after := "="; ; after = "," {
+		val, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if val == nil {
+			return nil, p.syntaxError("expected an expression after \"" + after + "\". Got \"" + p.data + "\"")
+		}
+		values = append(values, val)
+		if n == 1 || p.tt != COMMA || p.data != "," || p.assignmentAhead(p.currentIndex+1) > 0 ||
+			p.shortDeclarationAhead() || p.declarationAhead(p.currentIndex+1) {
+			break
+		}
+		p.consume() // consume the COMMA
+	}
+	return values, nil
+}
+
+// valueAt returns the value i of a list of values, or nil if there are less values.
+func valueAt(values []expression, i int) expression {
+	if i < len(values) {
+		return values[i]
+	}
+	return nil
+}
+
+// checkValueCount reports the assignment of a different number of values than variables.
+func (p *parser) checkValueCount(s span, variables []*Variable, values []expression) {
+	n := len(variables)
+	if count := valueCount(variables, values); n > 1 && count != n {
+		plural := "s"
+		if count == 1 {
+			plural = ""
+		}
+		p.report(newDiagnostic(s, codeType, fmt.Sprintf("assignment mismatch: %d variables but %d value%s", n, count, plural)))
+	}
+}
+
+// shortDeclarationAhead returns true if the tokens after the current one are a variable and a ":".
+func (p *parser) shortDeclarationAhead() bool {
+	if p.currentIndex+2 >= len(p.tokens) {
+		return false
+	}
+	next := p.tokens[p.currentIndex+1].tt
+	return (isVariableName(next) || next == NON_WORD_IDENTIFIER) && p.tokens[p.currentIndex+2].tt == COLON
+}
+
+// isVariableName returns true if a token of type tt can be the name of a variable. A single
+// uppercase letter is a type parameter in a type and a variable anywhere else: N: 10.
+func isVariableName(tt tokenType) bool {
+	return tt == IDENTIFIER || tt == GENERIC_TYPE_IDENTIFIER
+}
+
+// arguments parses an argument list into the invocation. The arguments are either all positional
+// or all named. Invalid arguments are reported and replaced by BadExpr nodes.
+//
+// parenthesis_invocation
+//
+//	::= "(" ")"
+//	| "(" expression ("," expression)* ")"
+//	| "(" expression ":" expression ("," expression ":" expression)* ")"
+func (p *parser) arguments(inv *Invocation) {
+	p.parenthesisList("argument", func(ap span, arg expression) {
+		switch a := arg.(type) {
+		case *ShortDeclaration:
+			if len(inv.args) > 0 {
+				p.report(newDiagnostic(a.span, codeSyntax, "can't mix positional and named arguments"))
+			}
+			inv.namedArgs = append(inv.namedArgs, &NamedArg{a.span, a.variable.name, a.value})
+		case *KeyValue:
+			p.report(newDiagnostic(a.span, codeSyntax, "expected an argument name. Got \""+a.key.stringValue()+"\""))
+			inv.args = append(inv.args, &BadExpr{a.span})
+		default:
+			if len(inv.namedArgs) > 0 {
+				p.report(newDiagnostic(p.spanFrom(ap), codeSyntax, "can't mix positional and named arguments"))
+			}
+			inv.args = append(inv.args, arg)
+		}
+	})
+}
+
+// parseParenthesisExp parses the expressions between parenthesis, current position is at the LPAREN.
+//
+// parenthesized_expressions ::= "(" (expression ("," expression)*)? ")"
+func (p *parser) parseParenthesisExp() (expression, error) {
+	pe := &ParenthesisExp{p.span, []expression{}, span{}}
+	pe.rparen = p.parenthesisList("expression", func(_ span, exp expression) {
+		pe.expressions = append(pe.expressions, exp)
+	})
+	return pe, nil
+}
+
+// parenthesisList parses a "," separated list of expressions between parenthesis and passes each
+// one to add along with the span where it starts. The current position is at the LPAREN.
+// Invalid elements are reported and replaced by BadExpr nodes. If the list isn't closed the missing
+// ")" is reported, the element describes the list elements in the error messages.
+// It returns the span of the RPAREN, or an empty span if it's missing.
+func (p *parser) parenthesisList(element string, add func(start span, exp expression)) span {
+	lparen := p.span
+	p.consume() // consume the LPAREN
+	p.parens++
+	defer func() { p.parens-- }()
+
+	for p.tt != RPAREN {
+		ep := p.span
+		exp, err := p.expression()
+		if err == nil && exp == nil {
+			err = p.syntaxError("expected an " + element + ". Got \"" + p.data + "\"")
+		}
+		if err != nil {
+			p.report(err)
+			p.synchronize()
+			exp = &BadExpr{p.spanFrom(ep)}
+		}
+		add(ep, exp)
+
+		switch p.tt {
+		case COMMA:
+			p.consume()
+		case RPAREN:
+		case RBRACE, RBRACKET, EOF:
+			// the enclosing block or array is closed, report the missing ")" unless the element
+			// already reported this token, and let it finish
+			if err == nil {
+				p.report(newDiagnostic(p.span, codeSyntax, "expected \",\" or \")\". Got \""+p.data+"\"").
+					withLabel(lparen, "the "+element+" list starts here"))
+			}
+			return span{}
+		default:
+			p.report(p.syntaxError("expected \",\" or \")\". Got \"" + p.data + "\""))
+			p.synchronize()
+			if p.tt == COMMA {
+				p.consume()
+			} else if p.tt != RPAREN {
+				return span{}
+			}
+		}
+	}
+	rparen := p.span
+	p.consume() // consume the RPAREN
+	return rparen
+}
+
+// statement returns nil if the tokens at the current position don't start a statement.
+//
+// statement ::= multiple_variable_definition
+//
+//	| variable_definition
+//	| variable_declaration
+//	| new_type_declaration
+//	| new_type_definition
+//	| variant_declaration
+//	| return
+//	| "break"
+//	| "continue"
+func (p *parser) statement() (statement, error) {
+	switch p.tt {
+	case RETURN:
+		return p.parseReturn()
+	case BREAK:
+		s := &Break{p.span}
+		p.consume()
+		return s, nil
+	case CONTINUE:
+		s := &Continue{p.span}
+		p.consume()
+		return s, nil
+	}
+	if p.declarationAhead(p.currentIndex) {
+		return p.parseVariableDeclaration()
+	}
+	if p.tt == TYPE_IDENTIFIER && p.currentIndex+1 < len(p.tokens) {
+		switch p.tokens[p.currentIndex+1].tt {
+		case HASH, COLON:
+			return p.parseTypeDeclaration()
+		case LBRACE:
+			return p.parseVariantDeclaration()
+		}
+	}
+	return nil, nil
+}
+
+// parseReturn parses a return and its values, the current position is at the "return". The values
+// end at the end of the line or of the block.
+//
+// return ::= "return" (expression ("," expression)*)?
+func (p *parser) parseReturn() (statement, error) {
+	start := p.span
+	p.consume() // consume the RETURN
+	r := &Return{span{}, []expression{}}
+	if !(p.tt == COMMA || p.tt == SEMICOLON || p.tt == RBRACE || p.tt == EOF) {
+		values, err := p.values(2)
+		if err != nil {
+			return nil, err
+		}
+		r.values = values
+	}
+	r.span = p.spanFrom(start)
+	return r, nil
+}
+
+// declarationAhead returns true if the token i is a variable followed by the start of a type:
+// `a Int`, `a []Int`, `a [String:Int]` or `a #()`. A "[" after a variable starts a type only if
+// it is followed by "]" or a type, otherwise it's an index like `a[0]`.
+func (p *parser) declarationAhead(i int) bool {
+	if i+1 >= len(p.tokens) || !isVariableName(p.tokens[i].tt) {
+		return false
+	}
+	switch p.tokens[i+1].tt {
+	case TYPE_IDENTIFIER, HASH:
+		return true
+	case LBRACKET:
+		return i+2 < len(p.tokens) && startsType(p.tokens[i+2].tt)
+	}
+	return false
+}
+
+func startsType(t tokenType) bool {
+	return t == RBRACKET || t == TYPE_IDENTIFIER || t == LBRACKET || t == HASH
+}
+
+// parseVariableDeclaration parses one or more typed variables and their values, if any.
+// The variables are declared with the given type, the values must be assignable to it.
+//
+// variable_declaration ::= variable type
+// variable_definition ::= variable_declaration "=" expression
+// multiple_variable_definition ::= variable_declaration ("," variable_declaration)* "=" expression ("," expression)*
+func (p *parser) parseVariableDeclaration() (statement, error) {
+	start := p.span
+	vd := &VariableDeclaration{span{}, []*Variable{}, []expression{}}
+	for {
+		v := &Variable{p.span, p.data, nil}
+		p.consume() // consume the variable
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		v.varType = t
+		vd.variables = append(vd.variables, v)
+		if p.tt != COMMA || p.data != "," || !p.declarationAhead(p.currentIndex+1) {
+			break
+		}
+		p.consume() // consume the COMMA
+	}
+	if p.tt != ASSIGN {
+		vd.span = p.spanFrom(start)
+		return vd, nil
+	}
+	p.consume() // consume the ASSIGN
+	if bt, ok := vd.variables[0].varType.(*BocType); ok && len(vd.variables) == 1 && bt.name == "" {
+		// the members of the signature are the parameters of the block: add #(x Int, y Int, Int) = { x + y }
+		params := map[string]*Variable{}
+		for _, v := range bt.variables {
+			if v.name != "" {
+				params[v.name] = v
+			}
+		}
+		p.scopes = append(p.scopes, params)
+		defer func() { p.scopes = p.scopes[:len(p.scopes)-1] }()
+	}
+	values, err := p.values(len(vd.variables))
+	if err != nil {
+		return nil, err
+	}
+	vd.values = values
+	vd.span = p.spanFrom(start)
+	p.checkValueCount(vd.span, vd.variables, vd.values)
+	p.checkValueTypes("definition", vd.variables, vd.values)
+	return vd, nil
+}
+
+// checkValueTypes reports the values of a definition or an assignment that can't be assigned to the
+// type of their variable. variables are the declared variables, construct names the one checked.
+func (p *parser) checkValueTypes(construct string, variables []*Variable, values []expression) {
+	types := make([]Type, len(values))
+	spans := make([]span, len(values))
+	for i, v := range values {
+		types[i] = v.dataType()
+		spans[i] = spanOf(v)
+	}
+	if tt, ok := types[0].(*TupleType); ok && len(values) == 1 && len(variables) > 1 {
+		types = tt.elemTypes
+		for len(spans) < len(types) {
+			spans = append(spans, spans[0])
+		}
+	}
+	if len(types) != len(variables) {
+		return // reported as an assignment mismatch
+	}
+	for i, v := range variables {
+		if ti, ok := valueAt(values, i).(*TypeInstantiation); ok {
+			ti.complete(v.varType)
+		}
+		if !assignable(v.varType, types[i]) {
+			p.report(newDiagnostic(spans[i], codeType,
+				fmt.Sprintf("cannot use %s value as %s in the %s of %s", types[i], v.varType, construct, v.name)).
+				withLabel(v.span, "declared as "+v.varType.String()+" here"))
+		}
+	}
+}
+
+// parseTypeDeclaration parses a user-defined type, the current position is at its name.
+// The type is declared before its signature and its body are parsed, so they can refer to it.
+// The members of the signature are variables of the body.
+//
+// new_type_declaration ::= type_identifier block_signature [ "=" block_literal ]
+// new_type_definition ::= type_identifier ":" block_literal
+func (p *parser) parseTypeDeclaration() (statement, error) {
+	start := p.span
+	td := &TypeDeclaration{span{}, &Variable{p.span, p.data, nil}, nil}
+	p.consume() // consume the TYPE_IDENTIFIER
+	if p.tt == COLON {
+		p.consume() // consume the COLON
+		if p.tt != LBRACE {
+			return nil, p.syntaxError("expected a block literal after \"" + td.variable.name + ":\". Got \"" + p.data + "\"")
+		}
+		p.consume() // consume the LBRACE
+		bt := &BocType{name: td.variable.name, variables: []*Variable{}}
+		td.variable.varType = bt
+		p.declare(td)
+		td.body = p.boc(RBRACE)
+		bodyType := td.body.dataType().(*BocType)
+		bt.variables, bt.result = bodyType.variables, bodyType.result
+		td.span = p.spanFrom(start)
+		return td, nil
+	}
+
+	// the type is declared before its signature, so its members can hold values of it: next []Node
+	bt := &BocType{name: td.variable.name, variables: []*Variable{}}
+	td.variable.varType = bt
+	p.declare(td)
+	if _, err := p.parseBlockSignature(bt); err != nil {
+		return nil, err
+	}
+	p.checkRecursiveMembers(bt)
+	if p.tt == ASSIGN {
+		p.consume() // consume the ASSIGN
+		if p.tt != LBRACE {
+			return nil, p.syntaxError("expected a block literal after \"=\". Got \"" + p.data + "\"")
+		}
+		p.consume() // consume the LBRACE
+		members := map[string]*Variable{}
+		for _, v := range bt.variables {
+			if v.name != "" {
+				members[v.name] = v
+			}
+		}
+		scope := map[string]*Variable{}
+		for name, v := range members {
+			scope[name] = v
+		}
+		for _, tp := range bt.typeParams {
+			scope[tp.name] = &Variable{td.variable.span, tp.name, tp}
+		}
+		p.scopes = append(p.scopes, scope)
+		td.body = p.boc(RBRACE)
+		p.scopes = p.scopes[:len(p.scopes)-1]
+		// the variables declared in the body, like methods, are members of the type too
+		for _, v := range td.body.dataType().(*BocType).variables {
+			if _, ok := members[v.name]; !ok {
+				bt.variables = append(bt.variables, v)
+			}
+		}
+	}
+	td.span = p.spanFrom(start)
+	return td, nil
+}
+
+// parseVariantDeclaration parses a variant type, the current position is at its name. The cases
+// are declared with the type, they construct its values: Some(1). An invalid case is reported and
+// the rest of the cases are skipped.
+//
+// variant_declaration ::= type_identifier "{" variant_case ("," variant_case)* "}"
+func (p *parser) parseVariantDeclaration() (statement, error) {
+	start := p.span
+	vt := &VariantType{name: p.data, cases: []*BocType{}}
+	vd := &VariantDeclaration{span{}, &Variable{p.span, p.data, vt}, []*Variable{}}
+	p.consume() // consume the TYPE_IDENTIFIER
+	lbrace := p.span
+	p.consume() // consume the LBRACE
+	// the variant is declared before its cases, so they can hold values of it: Cons(head Int, tail List)
+	p.scopes[len(p.scopes)-1][vt.name] = vd.variable
+	// the type parameters used by the cases are the ones of the variant: Option { Some(value T), None() }
+	defer p.genericDeclaration(&vt.typeParams)()
+	invalid := false
+	for {
+		for p.tt == COMMA {
+			p.consume()
+		}
+		if p.tt == RBRACE || p.tt == EOF {
+			break
+		}
+		if err := p.variantCase(vd); err != nil {
+			p.report(err)
+			p.skipBlock()
+			invalid = true
+			break
+		}
+	}
+	if p.tt != RBRACE {
+		return nil, newDiagnostic(p.span, codeSyntax, "expected \"}\". Got \""+p.data+"\"").
+			withLabel(lbrace, "the variant starts here")
+	}
+	p.consume() // consume the RBRACE
+	vd.span = p.spanFrom(start)
+	if len(vd.cases) == 0 && !invalid {
+		p.report(newDiagnostic(vd.span, codeType, "the variant "+vt.name+" has no cases"))
+	}
+	return vd, nil
+}
+
+// variantCase parses a case of a variant and adds it to the declaration, the current position is at its name.
+//
+// variant_case ::= type_identifier "(" (variable type ("," variable type)*)? ")"
+func (p *parser) variantCase(vd *VariantDeclaration) error {
+	vt := vd.variable.varType.(*VariantType)
+	if p.tt != TYPE_IDENTIFIER {
+		return p.syntaxError("expected a variant case. Got \"" + p.data + "\"")
+	}
+	c := &Variable{p.span, p.data, nil}
+	p.consume() // consume the TYPE_IDENTIFIER
+	if p.tt != LPAREN {
+		return p.syntaxError("expected \"(\" after the case " + c.name + ". Got \"" + p.data + "\"")
+	}
+	bt, err := p.signatureMembers(newBocType())
+	if err != nil {
+		return err
+	}
+	for _, v := range bt.variables {
+		if v.name == "" {
+			p.report(newDiagnostic(v.span, codeSyntax, "expected a name for the member of the case "+c.name).
+				withHint("name the value the case holds, like Some(value Int)"))
+		}
+	}
+	for _, other := range vd.cases {
+		if other.name == c.name {
+			p.report(newDiagnostic(c.span, codeType, "duplicate case \""+c.name+"\" in the variant "+vt.name).
+				withLabel(other.span, "first declared here"))
+		}
+	}
+	bt.name, bt.result = c.name, vt
+	c.varType = bt
+	vt.cases = append(vt.cases, bt)
+	vd.cases = append(vd.cases, c)
+	if p.tt != COMMA && p.tt != RBRACE {
+		return p.syntaxError("expected \",\" or \"}\". Got \"" + p.data + "\"")
+	}
+	return nil
+}
+
+// skipBlock skips the tokens until the "}" that closes the current block, without consuming it.
+// The skip can start inside a parenthesis or an array of the block, their closing tokens are skipped.
+func (p *parser) skipBlock() {
+	for depth := 0; p.tt != EOF; p.consume() {
+		switch p.tt {
+		case LBRACE, LBRACKET, LPAREN:
+			depth++
+		case RBRACKET, RPAREN:
+			depth = max(depth-1, 0)
+		case RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+	}
+}
+
+// parseBlockSignature parses a block signature into bt and returns it, the current position is at the "#".
+//
+// block_signature ::= "#" "(" ")" | "#" "(" type_member ("," type_member)* ")"
+func (p *parser) parseBlockSignature(bt *BocType) (*BocType, error) {
+	p.consume() // consume the HASH
+	if p.tt != LPAREN {
+		return nil, p.syntaxError("expected \"(\" after \"#\". Got \"" + p.data + "\"")
+	}
+	return p.signatureMembers(bt)
+}
+
+// checkRecursiveMembers reports the members of a user-defined type that hold a value of the type
+// itself, a value of it would never end. An array of them can be empty: next []Node.
+func (p *parser) checkRecursiveMembers(bt *BocType) {
+	for _, v := range bt.variables {
+		if t, ok := v.varType.(*BocType); ok && v.name != "" && (t == bt || t.generic == bt) {
+			p.report(newDiagnostic(v.span, codeType, fmt.Sprintf("the member %s of %s holds a value of %s, it would never end", v.name, bt.name, bt.name)).
+				withHint("hold them in an array, like " + v.name + " []" + bt.String() + ", or use a variant with a case without it"))
+		}
+	}
+}
+
+// signatureMembers parses the members of a block signature or a variant case into bt and returns it,
+// the current position is at the "(". The members without name are the result types of the block. The generic type
+// identifiers before the members are its type parameters, the ones used in the types of the members
+// are type parameters too: #(T, x T, y U) has the type parameters T and U.
+//
+// type_member ::= variable type | type | generic_type_identifier
+func (p *parser) signatureMembers(bt *BocType) (*BocType, error) {
+	p.consume() // consume the LPAREN
+	if p.generics == nil {
+		defer p.genericDeclaration(&bt.typeParams)()
+	}
+	var results []Type
+	for leading := true; p.tt != RPAREN; {
+		start := p.span
+		if leading && p.tt == GENERIC_TYPE_IDENTIFIER && p.currentIndex+1 < len(p.tokens) &&
+			(p.tokens[p.currentIndex+1].tt == COMMA || p.tokens[p.currentIndex+1].tt == RPAREN) {
+			// the leading type parameters are declared, they are not members: #(T, x T)
+			p.typeParameter(true)
+			p.consume()
+			if p.tt == COMMA {
+				p.consume()
+			}
+			continue
+		}
+		leading = false
+		name := ""
+		if p.tt == IDENTIFIER {
+			name = p.data
+			p.consume()
+		}
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			results = append(results, t)
+			bt.variables = append(bt.variables, &Variable{p.spanFrom(start), "", t})
+		} else {
+			for _, v := range bt.variables {
+				if v.name == name {
+					p.report(newDiagnostic(start, codeType, "duplicate member \""+name+"\" in the block signature").
+						withLabel(v.span, "first declared here"))
+				}
+			}
+			bt.variables = append(bt.variables, &Variable{start, name, t})
+		}
+		if p.tt == RPAREN {
+			break
+		}
+		if p.tt != COMMA {
+			return nil, p.syntaxError("expected \",\" or \")\". Got \"" + p.data + "\"")
+		}
+		p.consume() // consume the COMMA
+	}
+	p.consume() // consume the RPAREN
+	switch len(results) {
+	case 0:
+	case 1:
+		bt.result = results[0]
+	default:
+		bt.result = &TupleType{elemTypes: results}
+	}
+	return bt, nil
+}
+
+// genericDeclaration starts the declaration of a generic type or block whose type parameters are
+// added to params, and returns the function that ends it. The type parameters are declared in a
+// scope of their own, the nested signatures use the ones of the outermost declaration.
+func (p *parser) genericDeclaration(params *[]*GenericType) func() {
+	outer := p.generics
+	p.generics = params
+	p.scopes = append(p.scopes, map[string]*Variable{})
+	return func() {
+		p.scopes = p.scopes[:len(p.scopes)-1]
+		p.generics = outer
+	}
+}
+
+// typeParameter returns the type parameter named like the current token, declaring it in the
+// generic declaration being parsed if it's not declared yet. If declaration is true the type
+// parameter is declared even if an enclosing declaration has one with the same name, and it's
+// reported if it's declared twice. One used outside of a generic declaration is TBD.
+func (p *parser) typeParameter(declaration bool) Type {
+	if tp, ok := p.namedType(p.data).(*GenericType); ok {
+		if !declaration {
+			return tp
+		}
+		for _, own := range *p.generics {
+			if own == tp {
+				p.report(newDiagnostic(p.span, codeType, "duplicate type parameter \""+p.data+"\""))
+				return tp
+			}
+		}
+	}
+	if p.generics == nil {
+		p.report(newDiagnostic(p.span, codeType, "the type parameter "+p.data+" is not declared").
+			withHint("declare it in a block signature like #(T, x T) or use it in a type declaration"))
+		return newTBD()
+	}
+	tp := &GenericType{name: p.data}
+	*p.generics = append(*p.generics, tp)
+	p.scopes[len(p.scopes)-1][tp.name] = &Variable{p.span, tp.name, tp}
+	return tp
+}
+
+// namedType returns the type called name: a basic type or a user-defined type declared in the
+// current block or in an enclosing one, or TBD if it is unknown.
+func (p *parser) namedType(name string) Type {
+	if t := typeFromTokenData(name); !isTBD(t) {
+		return t
+	}
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if declared, ok := p.scopes[i][name]; ok {
+			return declared.varType
+		}
+	}
+	return newTBD()
+}
+
+// knownType returns the type called like the current token, see namedType. An unknown type is
+// reported and is TBD.
+func (p *parser) knownType(name string) Type {
+	t := p.namedType(name)
+	if isTBD(t) {
+		p.report(newDiagnostic(p.span, codeType, "unknown type "+name).
+			withHint("use a basic type (Int, Decimal, String or Bool) or declare " + name + " before using it"))
+	}
+	return t
+}
+
+// parseType parses a type, the current position is at its first token. Unknown type identifiers
+// are reported and are TBD.
+//
+// type ::= type_identifier | array_type | dictionary_type | block_signature
+// array_type ::= "[" "]" type
+// dictionary_type ::= "[" type ":" type "]"
+func (p *parser) parseType() (Type, error) {
+	switch p.tt {
+	case TYPE_IDENTIFIER:
+		start := p.span
+		t := p.knownType(p.data)
+		p.consume()
+		return p.typeArguments(start, t)
+	case GENERIC_TYPE_IDENTIFIER:
+		t := p.typeParameter(false)
+		p.consume()
+		return t, nil
+	case LBRACKET:
+		p.consume() // consume the LBRACKET
+		if p.tt == RBRACKET {
+			p.consume()
+			elemType, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			return &ArrayType{elemType: elemType}, nil
+		}
+		keyStart := p.span
+		keyType, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		p.checkKeyType(p.spanFrom(keyStart), keyType)
+		if p.tt != COLON {
+			return nil, p.syntaxError("expected \":\" after the key type. Got \"" + p.data + "\"")
+		}
+		p.consume()
+		valType, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if p.tt != RBRACKET {
+			return nil, p.syntaxError("expected \"]\". Got \"" + p.data + "\"")
+		}
+		p.consume()
+		return &DictType{keyType: keyType, valType: valType}, nil
+	case HASH:
+		bt := newBocType()
+		bt.signature = true
+		return p.parseBlockSignature(bt)
+	}
+	return nil, p.syntaxError("expected a type. Got \"" + p.data + "\"")
+}
+
+// checkKeyType reports a key type of a dictionary whose values can't be compared, the keys are
+// looked up by their value.
+func (p *parser) checkKeyType(s span, t Type) {
+	if !isComparable(t) {
+		p.report(newDiagnostic(s, codeType, fmt.Sprintf("cannot use %s as the key of a dictionary, its values can't be compared", t)).
+			withHint("use a key of Int, Decimal, String, Bool or a type that only holds them"))
+	}
+}
+
+// typeArguments parses the type arguments of a generic type and returns its instance, the current
+// position is after the name of the type. The other types are returned as they are. Inside its own
+// declaration the type takes its type parameters and is the declaration itself: tail List(T).
+//
+// generic_type ::= type_identifier "(" type ("," type)* ")"
+func (p *parser) typeArguments(start span, t Type) (Type, error) {
+	var name string
+	var params *[]*GenericType
+	switch t := t.(type) {
+	case *VariantType:
+		name, params = t.name, &t.typeParams
+	case *BocType:
+		name, params = t.name, &t.typeParams
+	}
+	// the type parameters of the type being declared are declared as they are used
+	own := params != nil && params == p.generics
+	if name == "" || len(*params) == 0 && !(own && p.tt == LPAREN) {
+		return t, nil
+	}
+	if p.tt != LPAREN {
+		p.report(newDiagnostic(start, codeType, "missing the type arguments of "+name).
+			withHint("pass the types its type parameters take, like " + instanceExample(name, len(*params))))
+		return t, nil
+	}
+	p.consume() // consume the LPAREN
+	var args []Type
+	for p.tt != RPAREN {
+		arg, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.tt == COMMA {
+			p.consume()
+		} else if p.tt != RPAREN {
+			return nil, p.syntaxError("expected \",\" or \")\". Got \"" + p.data + "\"")
+		}
+	}
+	p.consume() // consume the RPAREN
+	if own {
+		if !sameTypeParams(*params, args) {
+			p.report(newDiagnostic(p.spanFrom(start), codeType,
+				fmt.Sprintf("%s takes its own type parameters in its declaration, like %s", name, name+typeArgsString(typeArguments(*params, nil)))))
+		}
+		return t, nil
+	}
+	if len(args) != len(*params) {
+		p.report(newDiagnostic(p.spanFrom(start), codeType,
+			fmt.Sprintf("wrong number of type arguments for %s, want %d", name, len(*params))))
+		return t, nil
+	}
+	b := bindings{}
+	for i, tp := range *params {
+		b[tp] = args[i]
+	}
+	return b.substitute(t), nil
+}
+
+// sameTypeParams returns true if the types are the type parameters, in the same order.
+func sameTypeParams(params []*GenericType, types []Type) bool {
+	if len(params) != len(types) {
+		return false
+	}
+	for i, tp := range params {
+		if types[i] != tp {
+			return false
+		}
+	}
+	return true
 }
 
 func (p *parser) syntaxError(message string) error {
-	//p.currentIndex = len(p.tokens) - 1
-	return fmt.Errorf("[%s] %s", p.pos, message)
+	return newDiagnostic(p.span, codeSyntax, message)
 }
 
-func debugCurrentTokenAtPosition(tokens []Token, index int) string {
+func debugCurrentTokenAtPosition(files *fileSet, tokens []Token, index int) string {
 	ll := 1
 	var builder strings.Builder
 	builder.WriteString("Tokens:\n")
 	builder.WriteString(fmt.Sprintf("%d: ", ll))
 	for i, t := range tokens {
-		if ll != t.pos.line {
-			ll = t.pos.line
+		if line := files.position(t.span).line; ll != line {
+			ll = line
 			builder.WriteString("\n")
 			builder.WriteString(fmt.Sprintf("%d: ", ll))
 		}
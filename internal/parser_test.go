@@ -12,6 +12,7 @@ import (
 func TestParser_Parse(t *testing.T) {
 	tests := []struct {
 		name         string
+		source       string // the text the tokens are read from
 		path         []string
 		tokens       []Token
 		want         *Boc
@@ -19,21 +20,23 @@ func TestParser_Parse(t *testing.T) {
 		errorMessage string
 	}{
 		{
-			name: "Empty file",
-			path: []string{"empty"},
+			name:   "Empty file",
+			source: "",
+			path:   []string{"empty"},
 			tokens: []Token{
-				{pos(0, 0), EOF, "EOF"},
+				{sp(0, 0), EOF, "EOF"},
 			},
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "empty",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span:        sp(0, 0),
 							expressions: []expression{},
 							statements:  []statement{},
 						},
@@ -43,30 +46,32 @@ func TestParser_Parse(t *testing.T) {
 			},
 		},
 		{
-			name: "Nested directory",
-			path: []string{"parent", "simple"},
+			name:   "Nested directory",
+			source: "",
+			path:   []string{"parent", "simple"},
 			tokens: []Token{
-				{pos(0, 0), EOF, "EOF"},
+				{sp(0, 0), EOF, "EOF"},
 			},
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "parent",
 							varType: newBocType(),
 						},
 						value: &Boc{
 							expressions: []expression{
 								&ShortDeclaration{
-									pos: pos(0, 0),
+									span: span{},
 									variable: &Variable{
-										pos:     pos(0, 0),
+										span:    span{},
 										name:    "simple",
 										varType: newBocType(),
 									},
 									value: &Boc{
+										span:        sp(0, 0),
 										expressions: []expression{},
 										statements:  []statement{},
 									},
@@ -80,25 +85,27 @@ func TestParser_Parse(t *testing.T) {
 			},
 		},
 		{
-			name: "Literal expressions",
-			path: []string{"literals"},
+			name:   "Literal expressions",
+			source: `1`,
+			path:   []string{"literals"},
 			tokens: []Token{
-				{pos(1, 1), INTEGER, "1"},
-				{pos(1, 2), EOF, "EOF"},
+				{sp(0, 1), INTEGER, "1"},
+				{sp(1, 1), EOF, "EOF"},
 			},
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "literals",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 1),
 							expressions: []expression{
 								&BasicLit{
-									pos(1, 1),
+									sp(0, 1),
 									INTEGER,
 									"1",
 									&IntType{},
@@ -112,25 +119,27 @@ func TestParser_Parse(t *testing.T) {
 			},
 		},
 		{
-			name: "Literal expressions string",
-			path: []string{"string_literal"},
+			name:   "Literal expressions string",
+			source: `"Hello world"`,
+			path:   []string{"string_literal"},
 			tokens: []Token{
-				{pos(1, 1), STRING, "Hello world"},
-				{pos(1, 12), EOF, "EOF"},
+				{sp(0, 13), STRING, "Hello world"},
+				{sp(13, 13), EOF, "EOF"},
 			},
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "string_literal",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 13),
 							expressions: []expression{
 								&BasicLit{
-									pos(1, 1),
+									sp(0, 13),
 									STRING,
 									"Hello world",
 									&StringType{},
@@ -144,25 +153,28 @@ func TestParser_Parse(t *testing.T) {
 			},
 		},
 		{
-			name: "Block literal",
-			path: []string{"block_literal"},
+			name:   "Block literal",
+			source: `{}`,
+			path:   []string{"block_literal"},
 			tokens: []Token{
-				{pos(1, 1), LBRACE, "{"},
-				{pos(1, 2), RBRACE, "}"},
-				{pos(1, 3), EOF, "EOF"},
+				{sp(0, 1), LBRACE, "{"},
+				{sp(1, 2), RBRACE, "}"},
+				{sp(2, 2), EOF, "EOF"},
 			},
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "block_literal",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 2),
 							expressions: []expression{
 								&Boc{
+									span:        sp(0, 2),
 									expressions: []expression{},
 									statements:  []statement{},
 								},
@@ -175,33 +187,35 @@ func TestParser_Parse(t *testing.T) {
 			},
 		},
 		{
-			name: "Two literals",
-			path: []string{"two_literals"},
+			name:   "Two literals",
+			source: `1,"Hello world"`,
+			path:   []string{"two_literals"},
 			tokens: []Token{
-				{pos(1, 1), INTEGER, "1"},
-				{pos(1, 2), COMMA, ","},
-				{pos(1, 3), STRING, "Hello world"},
-				{pos(1, 14), EOF, "EOF"},
+				{sp(0, 1), INTEGER, "1"},
+				{sp(1, 2), COMMA, ","},
+				{sp(2, 15), STRING, "Hello world"},
+				{sp(15, 15), EOF, "EOF"},
 			}, want: &Boc{
 
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "two_literals",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 15),
 							expressions: []expression{
 								&BasicLit{
-									pos(1, 1),
+									sp(0, 1),
 									INTEGER,
 									"1",
 									&IntType{},
 								},
 								&BasicLit{
-									pos(1, 3),
+									sp(2, 15),
 									STRING,
 									"Hello world",
 									&StringType{},
@@ -215,43 +229,72 @@ func TestParser_Parse(t *testing.T) {
 			},
 		},
 		{
-			name: "Invalid expression expression",
-			path: []string{"invalid_expression"},
+			name:   "Invalid expression expression",
+			source: `12`,
+			path:   []string{"invalid_expression"},
 			tokens: []Token{
-				{pos(1, 1), INTEGER, "1"},
-				{pos(1, 2), INTEGER, "2"},
-				{pos(1, 3), EOF, "EOF"},
+				{sp(0, 1), INTEGER, "1"},
+				{sp(1, 2), INTEGER, "2"},
+				{sp(2, 2), EOF, "EOF"},
 			}, wantErr: true,
-			errorMessage: "[line: 1 col: 2] expected \",\" or \"}\". Got \"2\"",
+			errorMessage: "[invalid_expression: line: 1 col: 2] expected \",\" or \"}\". Got \"2\"",
+			want: &Boc{
+				expressions: []expression{
+					&ShortDeclaration{
+						span: span{},
+						variable: &Variable{
+							span:    span{},
+							name:    "invalid_expression",
+							varType: newBocType(),
+						},
+						value: &Boc{
+							span: sp(0, 2),
+							expressions: []expression{
+								&BasicLit{
+									sp(0, 1),
+									INTEGER,
+									"1",
+									&IntType{},
+								},
+								&BadExpr{sp(1, 2)},
+							},
+							statements: []statement{},
+						},
+					},
+				},
+				statements: []statement{},
+			},
 		},
 		{
-			name: "Two literals with new line",
-			path: []string{"two_literals_newline"},
+			name:   "Two literals with new line",
+			source: "1\n\"Hello world\"",
+			path:   []string{"two_literals_newline"},
 			tokens: []Token{
-				{pos(1, 1), INTEGER, "1"},
-				{pos(1, 2), COMMA, "\n"},
-				{pos(2, 1), STRING, "Hello world"},
-				{pos(2, 12), EOF, "EOF"},
+				{sp(0, 1), INTEGER, "1"},
+				{sp(1, 2), COMMA, "\n"},
+				{sp(2, 15), STRING, "Hello world"},
+				{sp(15, 15), EOF, "EOF"},
 			},
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "two_literals_newline",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 15),
 							expressions: []expression{
 								&BasicLit{
-									pos(1, 1),
+									sp(0, 1),
 									INTEGER,
 									"1",
 									&IntType{},
 								},
 								&BasicLit{
-									pos(2, 1),
+									sp(2, 15),
 									STRING,
 									"Hello world",
 									&StringType{},
@@ -265,28 +308,30 @@ func TestParser_Parse(t *testing.T) {
 			},
 		},
 		{
-			name: "Array literal []Int",
-			path: []string{"array_literal"},
+			name:   "Array literal []Int",
+			source: `[]Int`,
+			path:   []string{"array_literal"},
 			tokens: []Token{
-				{pos(1, 1), LBRACKET, "["},
-				{pos(1, 2), RBRACKET, "]"},
-				{pos(1, 3), TYPE_IDENTIFIER, "Int"},
-				{pos(1, 6), EOF, "EOF"},
+				{sp(0, 1), LBRACKET, "["},
+				{sp(1, 2), RBRACKET, "]"},
+				{sp(2, 5), TYPE_IDENTIFIER, "Int"},
+				{sp(5, 5), EOF, "EOF"},
 			},
 			want: &Boc{
 				statements: []statement{},
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "array_literal",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 5),
 							expressions: []expression{
 								&ArrayLit{
-									pos(1, 1),
+									sp(0, 5),
 									[]expression{},
 									&ArrayType{elemType: &IntType{}},
 								},
@@ -301,7 +346,9 @@ func TestParser_Parse(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := Parse(tt.path, tt.tokens)
+			files := &fileSet{}
+			files.add(strings.Join(tt.path, "/"), tt.source)
+			got, err := Parse(files, tt.path, tt.tokens)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Parse() error = \"%v\", wantErr %v", err, tt.wantErr)
 				return
@@ -336,21 +383,22 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "two_literals",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 14),
 							expressions: []expression{
 								&ArrayLit{
-									pos(1, 1),
+									sp(0, 6),
 									[]expression{},
 									&ArrayType{elemType: &IntType{}},
 								},
 								&BasicLit{
-									pos(2, 1),
+									sp(7, 14),
 									STRING,
 									"Hello",
 									&StringType{},
@@ -370,31 +418,32 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "array_literal",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 9),
 							expressions: []expression{
 								&ArrayLit{
-									pos(1, 1),
+									sp(0, 9),
 									[]expression{
 										&BasicLit{
-											pos(1, 2),
+											sp(1, 2),
 											INTEGER,
 											"1",
 											&IntType{},
 										},
 										&BasicLit{
-											pos(1, 5),
+											sp(4, 5),
 											INTEGER,
 											"2",
 											&IntType{},
 										},
 										&BasicLit{
-											pos(1, 8),
+											sp(7, 8),
 											INTEGER,
 											"3",
 											&IntType{},
@@ -417,51 +466,54 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "array_of_arrays_2",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 15),
 							expressions: []expression{
 								&ArrayLit{
-									pos(1, 1),
+									sp(0, 15),
 									[]expression{
 										&ArrayLit{
-											pos(1, 2),
+											sp(1, 7),
 											[]expression{
 												&BasicLit{
-													pos(1, 3),
+													sp(2, 3),
 													INTEGER,
 													"1",
 													&IntType{},
 												},
 												&BasicLit{
-													pos(1, 6),
+													sp(5, 6),
 													INTEGER,
 													"2",
 													&IntType{},
 												},
 											},
-											&ArrayType{elemType: &IntType{}}},
+											&ArrayType{elemType: &IntType{}},
+										},
 										&ArrayLit{
-											pos(1, 9),
+											sp(8, 14),
 											[]expression{
 												&BasicLit{
-													pos(1, 10),
+													sp(9, 10),
 													INTEGER,
 													"1",
 													&IntType{},
 												},
 												&BasicLit{
-													pos(1, 13),
+													sp(12, 13),
 													INTEGER,
 													"2",
 													&IntType{},
 												},
 											},
-											&ArrayType{elemType: &IntType{}}},
+											&ArrayType{elemType: &IntType{}},
+										},
 									},
 									&ArrayType{elemType: &IntType{}},
 								},
@@ -480,38 +532,41 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "array_of_arrays",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 15),
 							expressions: []expression{
 								&ArrayLit{
-									pos(1, 1),
+									sp(0, 15),
 									[]expression{
 										&ArrayLit{
-											pos(1, 2),
+											sp(1, 7),
 											[]expression{
 												&BasicLit{
-													pos(1, 3),
+													sp(2, 3),
 													INTEGER,
 													"1",
 													&IntType{},
 												},
 												&BasicLit{
-													pos(1, 6),
+													sp(5, 6),
 													INTEGER,
 													"2",
 													&IntType{},
 												},
 											},
-											&ArrayType{elemType: &IntType{}}},
+											&ArrayType{elemType: &IntType{}},
+										},
 										&ArrayLit{
-											pos(1, 10),
+											sp(9, 14),
 											[]expression{},
-											&ArrayType{elemType: &IntType{}}},
+											&ArrayType{elemType: &IntType{}},
+										},
 									},
 									&ArrayType{elemType: &IntType{}},
 								},
@@ -530,21 +585,23 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "array_of_blocks",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 10),
 							expressions: []expression{
 								&ArrayLit{
-									pos(1, 1),
+									sp(0, 10),
 									[]expression{
 										&Boc{
+											span: sp(1, 4),
 											expressions: []expression{
 												&BasicLit{
-													pos(1, 3),
+													sp(2, 3),
 													INTEGER,
 													"1",
 													&IntType{},
@@ -553,9 +610,10 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 											statements: []statement{},
 										},
 										&Boc{
+											span: sp(6, 9),
 											expressions: []expression{
 												&BasicLit{
-													pos(1, 8),
+													sp(7, 8),
 													INTEGER,
 													"2",
 													&IntType{},
@@ -581,16 +639,17 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "empty_dictionary_literal",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 11),
 							expressions: []expression{
 								&DictLit{
-									pos(1, 1),
+									sp(0, 11),
 									&DictType{
 										keyType: &StringType{},
 										valType: &IntType{},
@@ -606,6 +665,68 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 				statements: []statement{},
 			},
 		},
+		{
+			name:    "Empty array literal of arrays [][]Int",
+			parents: []string{"array_of_arrays_literal"},
+			source:  `[][]Int`,
+			want: &Boc{
+				expressions: []expression{
+					&ShortDeclaration{
+						span: span{},
+						variable: &Variable{
+							span:    span{},
+							name:    "array_of_arrays_literal",
+							varType: newBocType(),
+						},
+						value: &Boc{
+							span: sp(0, 7),
+							expressions: []expression{
+								&ArrayLit{
+									sp(0, 7),
+									[]expression{},
+									&ArrayType{elemType: &ArrayType{elemType: &IntType{}}},
+								},
+							},
+							statements: []statement{},
+						},
+					},
+				},
+				statements: []statement{},
+			},
+		},
+		{
+			name:    "Empty dictionary literal of arrays [String][]Int",
+			parents: []string{"dictionary_of_arrays_literal"},
+			source:  `[String][]Int`,
+			want: &Boc{
+				expressions: []expression{
+					&ShortDeclaration{
+						span: span{},
+						variable: &Variable{
+							span:    span{},
+							name:    "dictionary_of_arrays_literal",
+							varType: newBocType(),
+						},
+						value: &Boc{
+							span: sp(0, 13),
+							expressions: []expression{
+								&DictLit{
+									sp(0, 13),
+									&DictType{
+										keyType: &StringType{},
+										valType: &ArrayType{elemType: &IntType{}},
+									},
+									[]expression{},
+									[]expression{},
+								},
+							},
+							statements: []statement{},
+						},
+					},
+				},
+				statements: []statement{},
+			},
+		},
 		{
 			name:    "Dictionary literal [k1:v1 k2:v2]",
 			parents: []string{"dictionary_literal"},
@@ -613,40 +734,41 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "dictionary_literal",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 14),
 							expressions: []expression{
 								&DictLit{
-									pos(1, 1),
+									sp(0, 14),
 									&DictType{
 										keyType: newTBD(),
 										valType: newTBD(),
 									},
 									[]expression{
 										&Variable{
-											pos(1, 2),
+											sp(1, 3),
 											"k1",
 											newTBD(),
 										},
 										&Variable{
-											pos(1, 9),
+											sp(8, 10),
 											"k2",
 											newTBD(),
 										},
 									},
 									[]expression{
 										&Variable{
-											pos(1, 5),
+											sp(4, 6),
 											"v1",
 											newTBD(),
 										},
 										&Variable{
-											pos(1, 12),
+											sp(11, 13),
 											"v2",
 											newTBD(),
 										},
@@ -670,29 +792,30 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "dictionary_literal_type",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 76),
 							expressions: []expression{
 								&DictLit{
-									pos(1, 1),
+									sp(0, 76),
 									&DictType{
 										keyType: &StringType{},
 										valType: &ArrayType{elemType: &StringType{}},
 									},
 									[]expression{
 										&BasicLit{
-											pos(2, 5),
+											sp(6, 12),
 											STRING,
 											"name",
 											&StringType{},
 										},
 										&BasicLit{
-											pos(3, 5),
+											sp(25, 38),
 											STRING,
 											"type system",
 											&StringType{},
@@ -700,11 +823,10 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 									},
 									[]expression{
 										&ArrayLit{
-											pos(2, 13),
-
+											sp(14, 20),
 											[]expression{
 												&BasicLit{
-													pos(2, 14),
+													sp(15, 19),
 													STRING,
 													"Yz",
 													&StringType{},
@@ -713,22 +835,22 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 											&ArrayType{elemType: &StringType{}},
 										},
 										&ArrayLit{
-											pos(3, 20),
+											sp(40, 74),
 											[]expression{
 												&BasicLit{
-													pos(3, 21),
+													sp(41, 49),
 													STRING,
 													"static",
 													&StringType{},
 												},
 												&BasicLit{
-													pos(3, 31),
+													sp(51, 59),
 													STRING,
 													"strong",
 													&StringType{},
 												},
 												&BasicLit{
-													pos(3, 41),
+													sp(61, 73),
 													STRING,
 													"structural",
 													&StringType{},
@@ -753,23 +875,24 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "short_declaration",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 5),
 							expressions: []expression{
 								&ShortDeclaration{
-									pos(1, 1),
+									sp(0, 5),
 									&Variable{
-										pos(1, 1),
+										sp(0, 1),
 										"a",
 										&IntType{},
 									},
 									&BasicLit{
-										pos(1, 5),
+										sp(4, 5),
 										INTEGER,
 										"1",
 										&IntType{},
@@ -793,60 +916,66 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "short_declaration_block_array",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 81),
 							expressions: []expression{
 								&ShortDeclaration{
-									pos(1, 1),
+									sp(0, 81),
 									&Variable{
-										pos:     pos(1, 1),
-										name:    "language",
-										varType: newBocType(),
+										sp(0, 8),
+										"language",
+										&BocType{variables: []*Variable{
+											{sp(17, 21), "name", &StringType{}},
+											{sp(33, 41), "features", &ArrayType{elemType: &StringType{}}},
+										}, result: &ArrayType{elemType: &StringType{}}},
 									},
 									&Boc{
+										span: sp(10, 81),
 										expressions: []expression{
 											&ShortDeclaration{
-												pos(2, 6),
+												sp(17, 27),
 												&Variable{
-													pos(2, 6),
+													sp(17, 21),
 													"name",
 													&StringType{},
 												},
 												&BasicLit{
-													pos(2, 12),
+													sp(23, 27),
 													STRING,
 													"Yz",
 													&StringType{},
 												},
 											},
 											&ShortDeclaration{
-												pos(3, 6),
+												sp(33, 77),
 												&Variable{
-													pos:     pos(3, 6),
-													name:    "features",
-													varType: &ArrayType{elemType: &StringType{}},
-												}, &ArrayLit{
-													pos(3, 16),
+													sp(33, 41),
+													"features",
+													&ArrayType{elemType: &StringType{}},
+												},
+												&ArrayLit{
+													sp(43, 77),
 													[]expression{
 														&BasicLit{
-															pos(3, 17),
+															sp(44, 52),
 															STRING,
 															"static",
 															&StringType{},
 														},
 														&BasicLit{
-															pos(3, 27),
+															sp(54, 62),
 															STRING,
 															"strong",
 															&StringType{},
 														},
 														&BasicLit{
-															pos(3, 37),
+															sp(64, 76),
 															STRING,
 															"structural",
 															&StringType{},
@@ -876,43 +1005,45 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "closing",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 33),
 							expressions: []expression{
 								&ShortDeclaration{
-									pos(1, 1),
+									sp(0, 33),
 									&Variable{
-										pos(1, 1),
+										sp(0, 10),
 										"dictionary",
 										&DictType{
 											keyType: &StringType{},
-											valType: newTBD(),
+											valType: &BoolType{},
 										},
 									},
 									&DictLit{
-										pos(1, 13),
+										sp(12, 33),
 										&DictType{
 											keyType: &StringType{},
-											valType: newTBD(),
+											valType: &BoolType{},
 										},
 										[]expression{
 											&BasicLit{
-												pos(2, 3),
+												sp(16, 23),
 												STRING,
 												"ready",
 												&StringType{},
 											},
 										},
 										[]expression{
-											&Variable{
-												pos(2, 13),
+											&BasicLit{
+												sp(26, 31),
+												BOOLEAN,
 												"false",
-												newTBD(),
+												&BoolType{},
 											},
 										},
 									},
@@ -938,61 +1069,67 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "short_declaration_literal_array_dictionary",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 119),
 							expressions: []expression{
 								&ShortDeclaration{
-									pos(1, 1),
+									sp(0, 119),
 									&Variable{
-										pos(1, 1),
+										sp(0, 4),
 										"main",
-										newBocType(),
+										&BocType{variables: []*Variable{
+											{sp(16, 19), "msg", &StringType{}},
+											{sp(37, 42), "array", &ArrayType{elemType: &IntType{}}},
+											{sp(63, 73), "dictionary", &DictType{keyType: &StringType{}, valType: &BoolType{}}},
+										}, result: &DictType{keyType: &StringType{}, valType: &BoolType{}}},
 									},
 									&Boc{
+										span: sp(6, 119),
 										expressions: []expression{
 											&ShortDeclaration{
-												pos(2, 9),
+												sp(16, 28),
 												&Variable{
-													pos(2, 9),
+													sp(16, 19),
 													"msg",
 													&StringType{},
 												},
 												&BasicLit{
-													pos(2, 14),
+													sp(21, 28),
 													STRING,
 													"Hello",
 													&StringType{},
 												},
 											},
 											&ShortDeclaration{
-												pos(3, 9),
+												sp(37, 54),
 												&Variable{
-													pos(3, 9),
+													sp(37, 42),
 													"array",
 													&ArrayType{elemType: &IntType{}},
 												},
 												&ArrayLit{
-													pos(3, 16),
+													sp(44, 54),
 													[]expression{
 														&BasicLit{
-															pos(3, 17),
+															sp(45, 46),
 															INTEGER,
 															"1",
 															&IntType{},
 														},
 														&BasicLit{
-															pos(3, 20),
+															sp(48, 49),
 															INTEGER,
 															"2",
 															&IntType{},
 														},
 														&BasicLit{
-															pos(3, 23),
+															sp(51, 52),
 															INTEGER,
 															"3",
 															&IntType{},
@@ -1002,34 +1139,35 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 												},
 											},
 											&ShortDeclaration{
-												pos(4, 9),
+												sp(63, 117),
 												&Variable{
-													pos(4, 9),
+													sp(63, 73),
 													"dictionary",
 													&DictType{
 														keyType: &StringType{},
-														valType: newTBD(),
+														valType: &BoolType{},
 													},
 												},
 												&DictLit{
-													pos(4, 21),
+													sp(75, 117),
 													&DictType{
 														keyType: &StringType{},
-														valType: newTBD(),
+														valType: &BoolType{},
 													},
 													[]expression{
 														&BasicLit{
-															pos(5, 17),
+															sp(93, 100),
 															STRING,
 															"ready",
 															&StringType{},
 														},
 													},
 													[]expression{
-														&Variable{
-															pos(5, 26),
+														&BasicLit{
+															sp(102, 107),
+															BOOLEAN,
 															"false",
-															newTBD(),
+															&BoolType{},
 														},
 													},
 												},
@@ -1060,62 +1198,69 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "array_of_dictionaries",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 50),
 							expressions: []expression{
 								&ArrayLit{
-									pos(1, 1),
+									sp(0, 50),
 									[]expression{
 										&DictLit{
-											pos(2, 2),
+											sp(3, 25),
 											&DictType{
 												keyType: &StringType{},
-												valType: newTBD(),
+												valType: &BoolType{},
 											},
 											[]expression{
 												&BasicLit{
-													pos(3, 3),
+													sp(7, 14),
 													STRING,
 													"ready",
 													&StringType{},
 												},
 											},
 											[]expression{
-												&Variable{
-													pos(3, 13),
+												&BasicLit{
+													sp(17, 22),
+													BOOLEAN,
 													"false",
-													newTBD(),
+													&BoolType{},
 												},
 											},
 										},
 										&DictLit{
-											pos(5, 2),
+											sp(28, 48),
 											&DictType{
 												keyType: &StringType{},
-												valType: newTBD(),
+												valType: &BoolType{},
 											},
 											[]expression{
 												&BasicLit{
-													pos(6, 3),
+													sp(32, 38),
 													STRING,
 													"done",
 													&StringType{},
 												},
-											}, []expression{
-												&Variable{
-													pos(6, 12),
+											},
+											[]expression{
+												&BasicLit{
+													sp(41, 45),
+													BOOLEAN,
 													"true",
-													newTBD(),
+													&BoolType{},
 												},
 											},
 										},
 									},
-									&ArrayType{elemType: &DictType{keyType: &StringType{}, valType: newTBD()}},
+									&ArrayType{elemType: &DictType{
+										keyType: &StringType{},
+										valType: &BoolType{},
+									}},
 								},
 							},
 							statements: []statement{},
@@ -1132,23 +1277,24 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "variable",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 4),
 							expressions: []expression{
 								&ShortDeclaration{
-									pos(1, 1),
+									sp(0, 4),
 									&Variable{
-										pos(1, 1),
+										sp(0, 1),
 										"a",
 										&IntType{},
 									},
 									&BasicLit{
-										pos(1, 4),
+										sp(3, 4),
 										INTEGER,
 										"1",
 										&IntType{},
@@ -1159,7 +1305,6 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 						},
 					},
 				},
-
 				statements: []statement{},
 			},
 		},
@@ -1170,18 +1315,94 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 			want: &Boc{
 				expressions: []expression{
 					&ShortDeclaration{
-						pos: pos(0, 0),
+						span: span{},
 						variable: &Variable{
-							pos:     pos(0, 0),
+							span:    span{},
 							name:    "variable",
 							varType: newBocType(),
 						},
 						value: &Boc{
+							span: sp(0, 1),
 							expressions: []expression{
 								&Variable{
-									pos:     pos(1, 1),
-									name:    "a",
-									varType: newTBD(),
+									sp(0, 1),
+									"a",
+									newTBD(),
+								},
+							},
+							statements: []statement{},
+						},
+					},
+				},
+				statements: []statement{},
+			},
+		},
+		{
+			name:    "Chained invocation",
+			parents: []string{"invocation"},
+			source:  `foo(1)(x: 2)`,
+			want: &Boc{
+				expressions: []expression{
+					&ShortDeclaration{
+						span: span{},
+						variable: &Variable{
+							span:    span{},
+							name:    "invocation",
+							varType: newBocType(),
+						},
+						value: &Boc{
+							span: sp(0, 12),
+							expressions: []expression{
+								&Invocation{
+									sp(0, 12),
+									&Invocation{
+										sp(0, 6),
+										&Variable{sp(0, 3), "foo", newTBD()},
+										[]expression{
+											&BasicLit{sp(4, 5), INTEGER, "1", &IntType{}},
+										},
+										[]*NamedArg{},
+									},
+									[]expression{},
+									[]*NamedArg{
+										{sp(7, 11), "x", &BasicLit{sp(10, 11), INTEGER, "2", &IntType{}}},
+									},
+								},
+							},
+							statements: []statement{},
+						},
+					},
+				},
+				statements: []statement{},
+			},
+		},
+		{
+			name:    "Non-parenthesis invocation",
+			parents: []string{"infix"},
+			source:  `a + 1`,
+			want: &Boc{
+				expressions: []expression{
+					&ShortDeclaration{
+						span: span{},
+						variable: &Variable{
+							span:    span{},
+							name:    "infix",
+							varType: newBocType(),
+						},
+						value: &Boc{
+							span: sp(0, 5),
+							expressions: []expression{
+								&Invocation{
+									sp(0, 5),
+									&MemberAccess{
+										sp(0, 3),
+										&Variable{sp(0, 1), "a", newTBD()},
+										&Variable{sp(2, 3), "+", newTBD()},
+									},
+									[]expression{
+										&BasicLit{sp(4, 5), INTEGER, "1", &IntType{}},
+									},
+									[]*NamedArg{},
 								},
 							},
 							statements: []statement{},
@@ -1191,16 +1412,156 @@ func TestParse_TokenizeAndParse(t *testing.T) {
 				statements: []statement{},
 			},
 		},
+		{
+			name:    "Method invocation resolves the member type",
+			parents: []string{"member"},
+			source:  `p: {x: 1}, p.x(2)`,
+			want: &Boc{
+				expressions: []expression{
+					&ShortDeclaration{
+						span: span{},
+						variable: &Variable{
+							span:    span{},
+							name:    "member",
+							varType: newBocType(),
+						},
+						value: &Boc{
+							span: sp(0, 17),
+							expressions: []expression{
+								&ShortDeclaration{
+									sp(0, 9),
+									&Variable{
+										sp(0, 1),
+										"p",
+										&BocType{variables: []*Variable{{sp(4, 5), "x", &IntType{}}}, result: &IntType{}},
+									},
+									&Boc{
+										span: sp(3, 9),
+										expressions: []expression{
+											&ShortDeclaration{
+												sp(4, 8),
+												&Variable{sp(4, 5), "x", &IntType{}},
+												&BasicLit{sp(7, 8), INTEGER, "1", &IntType{}},
+											},
+										},
+										statements: []statement{},
+									},
+								},
+								&Invocation{
+									sp(11, 17),
+									&MemberAccess{
+										sp(11, 14),
+										&Variable{
+											sp(11, 12),
+											"p",
+											&BocType{variables: []*Variable{{sp(4, 5), "x", &IntType{}}}, result: &IntType{}},
+										},
+										&Variable{sp(13, 14), "x", &IntType{}},
+									},
+									[]expression{
+										&BasicLit{sp(15, 16), INTEGER, "2", &IntType{}},
+									},
+									[]*NamedArg{},
+								},
+							},
+							statements: []statement{},
+						},
+					},
+				},
+				statements: []statement{},
+			},
+		},
+		{
+			name:    "Swap assignment",
+			parents: []string{"swap"},
+			source:  `a: 1; b: 2; a, b = b, a`,
+			want: &Boc{
+				expressions: []expression{
+					&ShortDeclaration{
+						span: span{},
+						variable: &Variable{
+							span:    span{},
+							name:    "swap",
+							varType: newBocType(),
+						},
+						value: &Boc{
+							span: sp(0, 23),
+							expressions: []expression{
+								&ShortDeclaration{
+									sp(0, 4),
+									&Variable{sp(0, 1), "a", &IntType{}},
+									&BasicLit{sp(3, 4), INTEGER, "1", &IntType{}},
+								},
+								&ShortDeclaration{
+									sp(6, 10),
+									&Variable{sp(6, 7), "b", &IntType{}},
+									&BasicLit{sp(9, 10), INTEGER, "2", &IntType{}},
+								},
+								&Assignment{
+									sp(12, 23),
+									[]*Variable{
+										{sp(12, 13), "a", &IntType{}},
+										{sp(15, 16), "b", &IntType{}},
+									},
+									[]expression{
+										&Variable{sp(19, 20), "b", &IntType{}},
+										&Variable{sp(22, 23), "a", &IntType{}},
+									},
+								},
+							},
+							statements: []statement{},
+						},
+					},
+				},
+				statements: []statement{},
+			},
+		},
+		{
+			name:    "Multiple variable definition",
+			parents: []string{"define"},
+			source:  `a Int, b []String = 1, []String`,
+			want: &Boc{
+				expressions: []expression{
+					&ShortDeclaration{
+						span: span{},
+						variable: &Variable{
+							span:    span{},
+							name:    "define",
+							varType: newBocType(),
+						},
+						value: &Boc{
+							span:        sp(0, 31),
+							expressions: []expression{},
+							statements: []statement{
+								&VariableDeclaration{
+									sp(0, 31),
+									[]*Variable{
+										{sp(0, 1), "a", &IntType{}},
+										{sp(7, 8), "b", &ArrayType{elemType: &StringType{}}},
+									},
+									[]expression{
+										&BasicLit{sp(20, 21), INTEGER, "1", &IntType{}},
+										&ArrayLit{sp(23, 31), []expression{}, &ArrayType{elemType: &StringType{}}},
+									},
+								},
+							},
+						},
+					},
+				},
+				statements: []statement{},
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tokens, err := Tokenize(tt.parents, tt.source)
+			files := &fileSet{}
+			tokens, err := Tokenize(files, tt.parents, tt.source)
 			if err != nil {
 				t.Errorf("Tokenize() error = \"%v\"", err)
 				return
 			}
-			got, err := Parse(tt.parents, tokens)
+			got, err := Parse(files, tt.parents, tokens)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("%s\nParse() error = \"%v\", wantErr %v", tt.source, err, tt.wantErr)
 				return
@@ -1242,12 +1603,13 @@ func TestParse_FromSource(t *testing.T) {
 			if err != nil {
 				t.Errorf("ReadFile() error = \"%v\"", err)
 			}
-			tokens, err := Tokenize([]string{file.Name()}, string(source))
+			files := &fileSet{}
+			tokens, err := Tokenize(files, []string{file.Name()}, string(source))
 			if err != nil {
 				t.Errorf("Tokenize() error = \"%v\"", err)
 				return
 			}
-			got, err := Parse([]string{file.Name()}, tokens)
+			got, err := Parse(files, []string{file.Name()}, tokens)
 			if err != nil {
 				t.Errorf("Parse() error = \"%v\"", err)
 				return
@@ -1287,3 +1649,1007 @@ func TestParse_FromSource(t *testing.T) {
 	}
 
 }
+
+func TestParse_SyntaxErrorRecovery(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		wantErrors []string
+		wantAst    string
+	}{
+		{
+			name: "Errors in several expressions",
+			source: `a: [1 2, 3]
+b: {1 2}
+c: 4`,
+			wantErrors: []string{
+				"[recovery: line: 1 col: 7] expected \",\" or \"]\". Got \"2\"",
+				"[recovery: line: 2 col: 7] expected \",\" or \"}\". Got \"2\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: a varType: ArrayType(IntType) )
+        ArrayLit(
+          arrayType: ArrayType(IntType)
+          expressions: [
+            BasicLit( tt: int value: 1 basicType: IntType )
+            BadExpr
+            BasicLit( tt: int value: 3 basicType: IntType )
+          ]
+        )
+      )
+      ShortDeclaration(
+        Var( name: b varType: BocType )
+        Boc(
+          BasicLit( tt: int value: 1 basicType: IntType )
+          BadExpr
+        )
+      )
+      ShortDeclaration(
+        Var( name: c varType: IntType )
+        BasicLit( tt: int value: 4 basicType: IntType )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Missing expression after colon",
+			source: `a: , b: 1`,
+			wantErrors: []string{
+				"[recovery: line: 1 col: 4] expected an expression after \":\". Got \",\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      BadExpr
+      ShortDeclaration(
+        Var( name: b varType: IntType )
+        BasicLit( tt: int value: 1 basicType: IntType )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Skipped dictionary entry",
+			source: `d: ["a": 1 2, "b": 3]`,
+			wantErrors: []string{
+				"[recovery: line: 1 col: 12] expected \",\" or \"]\". Got \"2\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: d varType: DictType( key: StringType value: IntType) )
+        DictLit(
+          dictType: DictType( key: StringType value: IntType )
+          keys: [ BasicLit( tt: str value: a basicType: StringType ) BadExpr BasicLit( tt: str value: b basicType: StringType ) ]
+          values: [ BasicLit( tt: int value: 1 basicType: IntType ) BadExpr BasicLit( tt: int value: 3 basicType: IntType ) ]
+        )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Semicolon in a literal",
+			source: "a: [1; 2]\nb: [c ;]\n[1 ;",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 6] expected \",\" or \"]\". Got \";\"",
+				"[recovery: line: 2 col: 7] expected \",\" or \"]\". Got \";\"",
+				"[recovery: line: 3 col: 4] expected \",\" or \"]\". Got \";\"",
+				"[recovery: line: 3 col: 5] expected an expression. Got \"EOF\"",
+				"[recovery: line: 3 col: 5] expected \",\" or \"]\". Got \"EOF\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: a varType: ArrayType(IntType) )
+        ArrayLit(
+          arrayType: ArrayType(IntType)
+          expressions: [
+            BasicLit( tt: int value: 1 basicType: IntType )
+            BadExpr
+            BasicLit( tt: int value: 2 basicType: IntType )
+          ]
+        )
+      )
+      ShortDeclaration(
+        Var( name: b varType: ArrayType(TBD) )
+        ArrayLit(
+          arrayType: ArrayType(TBD)
+          expressions: [ Var( name: c varType: TBD ) BadExpr ]
+        )
+      )
+      ArrayLit(
+        arrayType: ArrayType(IntType)
+        expressions: [ BasicLit( tt: int value: 1 basicType: IntType ) BadExpr BadExpr ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid assignments",
+			source: "z = 1\na: 1\na = \"s\"\nb, a = a, 2",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 1] undeclared variable z\nHint: declare it before assigning it, like z: value",
+				"[recovery: line: 3 col: 5] cannot use String value as Int in the assignment of a",
+				"[recovery: line: 4 col: 1] undeclared variable b\nHint: declare it before assigning it, like b: value",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      Assignment(
+        variables: [ Var( name: z varType: TBD ) ]
+        values: [ BasicLit( tt: int value: 1 basicType: IntType ) ]
+      )
+      ShortDeclaration(
+        Var( name: a varType: IntType )
+        BasicLit( tt: int value: 1 basicType: IntType )
+      )
+      Assignment(
+        variables: [ Var( name: a varType: IntType ) ]
+        values: [ BasicLit( tt: str value: s basicType: StringType ) ]
+      )
+      Assignment(
+        variables: [ Var( name: b varType: TBD ) Var( name: a varType: IntType ) ]
+        values: [ Var( name: a varType: IntType ) BasicLit( tt: int value: 2 basicType: IntType ) ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Unknown types",
+			source: "a Foo\nb []Strng = [\"x\"]\nc: [Key]Int",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 3] unknown type Foo\nHint: use a basic type (Int, Decimal, String or Bool) or declare Foo before using it",
+				"[recovery: line: 2 col: 5] unknown type Strng\nHint: use a basic type (Int, Decimal, String or Bool) or declare Strng before using it",
+				"[recovery: line: 3 col: 5] unknown type Key\nHint: use a basic type (Int, Decimal, String or Bool) or declare Key before using it",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: c varType: DictType( key: TBD value: IntType) )
+        DictLit( dictType: DictType( key: TBD value: IntType ) keys: [ ] values: [ ] )
+      )
+      VariableDeclaration(
+        variables: [ Var( name: a varType: TBD ) ]
+        values: [ ]
+      )
+      VariableDeclaration(
+        variables: [ Var( name: b varType: ArrayType(TBD) ) ]
+        values: [ ArrayLit( arrayType: ArrayType(StringType) expressions: [ BasicLit( tt: str value: x basicType: StringType ) ] ) ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Dictionary keys that can't be compared",
+			source: "a [[]Int:String]\nPair #(xs []Int)\nb: [Pair]Int\nc [[String:Int]:Int]",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 4] cannot use []Int as the key of a dictionary, its values can't be compared\nHint: use a key of Int, Decimal, String, Bool or a type that only holds them",
+				"[recovery: line: 3 col: 5] cannot use Pair as the key of a dictionary, its values can't be compared\nHint: use a key of Int, Decimal, String, Bool or a type that only holds them",
+				"[recovery: line: 4 col: 4] cannot use [String:Int] as the key of a dictionary, its values can't be compared\nHint: use a key of Int, Decimal, String, Bool or a type that only holds them",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: b varType: DictType( key: BocType(Pair) value: IntType) )
+        DictLit( dictType: DictType( key: BocType(Pair) value: IntType ) keys: [ ] values: [ ] )
+      )
+      VariableDeclaration(
+        variables: [ Var( name: a varType: DictType( key: ArrayType(IntType) value: StringType) ) ]
+        values: [ ]
+      )
+      TypeDeclaration(
+        name: Pair
+        variables: [ Var( name: xs varType: ArrayType(IntType) ) ]
+      )
+      VariableDeclaration(
+        variables: [ Var( name: c varType: DictType( key: DictType( key: StringType value: IntType) value: IntType) ) ]
+        values: [ ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Type holding itself",
+			source: "Bad #(value Int, next Bad)",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 18] the member next of Bad holds a value of Bad, it would never end\nHint: hold them in an array, like next []Bad, or use a variant with a case without it",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      TypeDeclaration(
+        name: Bad
+        variables: [ Var( name: value varType: IntType ) Var( name: next varType: BocType(Bad) ) ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid variant case followed by another error",
+			source: "Shape { Circle(r Int Square()), Dot() }\nx: [1 2]",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 22] expected \",\" or \")\". Got \"Square\"",
+				"[recovery: line: 2 col: 7] expected \",\" or \"]\". Got \"2\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: x varType: ArrayType(IntType) )
+        ArrayLit(
+          arrayType: ArrayType(IntType)
+          expressions: [ BasicLit( tt: int value: 1 basicType: IntType ) BadExpr ]
+        )
+      )
+      VariantDeclaration( name: Shape cases: [ ] )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Nested errors and stray closing bracket",
+			source: `[[1 2], 3] ], {x: }`,
+			wantErrors: []string{
+				"[recovery: line: 1 col: 5] expected \",\" or \"]\". Got \"2\"",
+				"[recovery: line: 1 col: 9] the elements of the array have different types: []Int and Int\nHint: write values of the same type",
+				"[recovery: line: 1 col: 12] expected \",\" or \"}\". Got \"]\"",
+				"[recovery: line: 1 col: 19] expected an expression after \":\". Got \"}\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ArrayLit(
+        arrayType: ArrayType(IntType)
+        expressions: [
+          ArrayLit(
+            arrayType: ArrayType(IntType)
+            expressions: [
+              BasicLit( tt: int value: 1 basicType: IntType )
+              BadExpr
+            ]
+          )
+          BasicLit( tt: int value: 3 basicType: IntType )
+        ]
+      )
+      BadExpr
+      Boc(
+        BadExpr
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Unterminated block",
+			source: `a: {1, 2`,
+			wantErrors: []string{
+				"[recovery: line: 1 col: 9] expected \"}\". Got \"EOF\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: a varType: BocType )
+        Boc(
+          BasicLit( tt: int value: 1 basicType: IntType )
+          BasicLit( tt: int value: 2 basicType: IntType )
+        )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid arguments",
+			source: `foo(1 2), bar(1, x: 2), baz("k": 1)`,
+			wantErrors: []string{
+				"[recovery: line: 1 col: 7] expected \",\" or \")\". Got \"2\"",
+				"[recovery: line: 1 col: 18] can't mix positional and named arguments",
+				"[recovery: line: 1 col: 29] expected an argument name. Got \"k\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      Invocation(
+        callee: Var( name: foo varType: TBD )
+        args: [ BasicLit( tt: int value: 1 basicType: IntType ) ]
+        namedArgs: [ ]
+      )
+      Invocation(
+        callee: Var( name: bar varType: TBD )
+        args: [ BasicLit( tt: int value: 1 basicType: IntType ) ]
+        namedArgs: [ NamedArg( name: x BasicLit( tt: int value: 2 basicType: IntType ) ) ]
+      )
+      Invocation(
+        callee: Var( name: baz varType: TBD )
+        args: [ BadExpr ]
+        namedArgs: [ ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Argument list closes an unclosed array",
+			source: `a: {foo(1, [2 3)}`,
+			wantErrors: []string{
+				"[recovery: line: 1 col: 15] expected \",\" or \"]\". Got \"3\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: a varType: BocType )
+        Boc(
+          Invocation(
+            callee: Var( name: foo varType: TBD )
+            args: [
+              BasicLit( tt: int value: 1 basicType: IntType )
+              ArrayLit(
+                arrayType: ArrayType(IntType)
+                expressions: [ BasicLit( tt: int value: 2 basicType: IntType ) BadExpr ]
+              )
+            ]
+            namedArgs: [ ]
+          )
+        )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Unclosed argument list",
+			source: `a: {foo(1, 2}`,
+			wantErrors: []string{
+				"[recovery: line: 1 col: 13] expected \",\" or \")\". Got \"}\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: a varType: BocType )
+        Boc(
+          Invocation(
+            callee: Var( name: foo varType: TBD )
+            args: [
+              BasicLit( tt: int value: 1 basicType: IntType )
+              BasicLit( tt: int value: 2 basicType: IntType )
+            ]
+            namedArgs: [ ]
+          )
+        )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Missing operand",
+			source: "a: b +, c: [1 -]",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 7] expected an expression after \"+\". Got \",\"",
+				"[recovery: line: 1 col: 16] expected an expression after \"-\". Got \"]\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      BadExpr
+      ShortDeclaration(
+        Var( name: c varType: ArrayType(TBD) )
+        ArrayLit(
+          arrayType: ArrayType(TBD)
+          expressions: [ BadExpr ]
+        )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Missing member name",
+			source: "a., b: a.1",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 3] expected a member name after \".\". Got \",\"",
+				"[recovery: line: 1 col: 10] expected a member name after \".\". Got \"1\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      BadExpr
+      BadExpr
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid parenthesis",
+			source: "a: (1 2), b: {(3, }",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 7] expected \",\" or \")\". Got \"2\"",
+				"[recovery: line: 1 col: 19] expected an expression. Got \"}\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: a varType: IntType )
+        ParenthesisExp(
+          type: IntType
+          expressions: [ BasicLit( tt: int value: 1 basicType: IntType ) ]
+        )
+      )
+      ShortDeclaration(
+        Var( name: b varType: BocType )
+        Boc(
+          ParenthesisExp(
+            type: TupleType(IntType, TBD)
+            expressions: [ BasicLit( tt: int value: 3 basicType: IntType ) BadExpr ]
+          )
+        )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid assignments",
+			source: "a: 1, b: 2\na = 1, b = 2\na, b = 1\na, b = 1, 2, 3\na =",
+			wantErrors: []string{
+				"[recovery: line: 2 col: 8] expected \";\" or a line break between assignments. Got \",\"\n" +
+					"Hint: assign several variables at once with a, b = 1, 2",
+				"[recovery: line: 3 col: 1] assignment mismatch: 2 variables but 1 value",
+				"[recovery: line: 4 col: 1] assignment mismatch: 2 variables but 3 values",
+				"[recovery: line: 5 col: 4] expected an expression after \"=\". Got \"EOF\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration( Var( name: a varType: IntType ) BasicLit( tt: int value: 1 basicType: IntType ) )
+      ShortDeclaration( Var( name: b varType: IntType ) BasicLit( tt: int value: 2 basicType: IntType ) )
+      Assignment(
+        variables: [ Var( name: a varType: IntType ) ]
+        values: [ BasicLit( tt: int value: 1 basicType: IntType ) ]
+      )
+      Assignment(
+        variables: [ Var( name: b varType: IntType ) ]
+        values: [ BasicLit( tt: int value: 2 basicType: IntType ) ]
+      )
+      Assignment(
+        variables: [ Var( name: a varType: IntType ) Var( name: b varType: IntType ) ]
+        values: [ BasicLit( tt: int value: 1 basicType: IntType ) ]
+      )
+      Assignment(
+        variables: [ Var( name: a varType: IntType ) Var( name: b varType: IntType ) ]
+        values: [
+          BasicLit( tt: int value: 1 basicType: IntType )
+          BasicLit( tt: int value: 2 basicType: IntType )
+          BasicLit( tt: int value: 3 basicType: IntType )
+        ]
+      )
+      BadExpr
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid variable declarations",
+			source: "a [String Int]\nb Int = \"b\"\nc Int, d Int = 1\ne #(x Int",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 11] expected \":\" after the key type. Got \"Int\"",
+				"[recovery: line: 2 col: 9] cannot use String value as Int in the definition of b",
+				"[recovery: line: 3 col: 1] assignment mismatch: 2 variables but 1 value",
+				"[recovery: line: 4 col: 10] expected \",\" or \")\". Got \"EOF\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      BadStmt
+      VariableDeclaration(
+        variables: [ Var( name: b varType: IntType ) ]
+        values: [ BasicLit( tt: str value: b basicType: StringType ) ]
+      )
+      VariableDeclaration(
+        variables: [ Var( name: c varType: IntType ) Var( name: d varType: IntType ) ]
+        values: [ BasicLit( tt: int value: 1 basicType: IntType ) ]
+      )
+      BadStmt
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid type declarations",
+			source: "Point #(x Int, x Int)\nSize: 1\np Point = 1",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 16] duplicate member \"x\" in the block signature",
+				"[recovery: line: 2 col: 7] expected a block literal after \"Size:\". Got \"1\"",
+				"[recovery: line: 3 col: 11] cannot use Int value as Point in the definition of p",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      TypeDeclaration(
+        name: Point
+        variables: [ Var( name: x varType: IntType ) Var( name: x varType: IntType ) ]
+      )
+      BadStmt
+      VariableDeclaration(
+        variables: [ Var( name: p varType: BocType(Point) ) ]
+        values: [ BasicLit( tt: int value: 1 basicType: IntType ) ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid variants",
+			source: "Option { Some(Int), Some(), none() }\nEmpty {}\nShape { Circle(radius Decimal) Square() }",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 15] expected a name for the member of the case Some\n" +
+					"Hint: name the value the case holds, like Some(value Int)",
+				"[recovery: line: 1 col: 21] duplicate case \"Some\" in the variant Option",
+				"[recovery: line: 1 col: 29] expected a variant case. Got \"none\"",
+				"[recovery: line: 2 col: 1] the variant Empty has no cases",
+				"[recovery: line: 3 col: 32] expected \",\" or \"}\". Got \"Square\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      VariantDeclaration(
+        name: Option
+        cases: [ Some( Var( name: varType: IntType ) ) Some( ) ]
+      )
+      VariantDeclaration( name: Empty cases: [ ] )
+      VariantDeclaration(
+        name: Shape
+        cases: [ Circle( Var( name: radius varType: DecimalType ) ) ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid when",
+			source: "a: when 1\nb: when { 1 => 2 }, { _ => 3 }, { 1 > 0 => 4 }\nc: when { 1 > 0 => 1 }, { _ => \"one\" }\nd: when { 1 > 0 2 => 5 }, { _ => 6 }",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 9] expected a case after \"when\" like { condition => value }. Got \"1\"",
+				"[recovery: line: 2 col: 11] expected a Bool condition. Got Int",
+				"[recovery: line: 2 col: 33] unreachable case after the default case",
+				"[recovery: line: 3 col: 29] the cases of when have different types: Int and String",
+				"[recovery: line: 4 col: 17] expected \"=>\" after the condition. Got \"2\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      BadExpr
+      ShortDeclaration(
+        Var( name: b varType: IntType )
+        When(
+          type: IntType
+          case: BasicLit( tt: int value: 1 basicType: IntType ) Boc( BasicLit( tt: int value: 2 basicType: IntType ) )
+          case: _ Boc( BasicLit( tt: int value: 3 basicType: IntType ) )
+          case: Invocation( callee: MemberAccess( receiver: BasicLit( tt: int value: 1 basicType: IntType ) member: Var( name: > varType: BocType ) ) args: [ BasicLit( tt: int value: 0 basicType: IntType ) ] namedArgs: [ ] ) Boc( BasicLit( tt: int value: 4 basicType: IntType ) )
+        )
+      )
+      ShortDeclaration(
+        Var( name: c varType: TBD )
+        When(
+          type: TBD
+          case: Invocation( callee: MemberAccess( receiver: BasicLit( tt: int value: 1 basicType: IntType ) member: Var( name: > varType: BocType ) ) args: [ BasicLit( tt: int value: 0 basicType: IntType ) ] namedArgs: [ ] ) Boc( BasicLit( tt: int value: 1 basicType: IntType ) )
+          case: _ Boc( BasicLit( tt: str value: one basicType: StringType ) )
+        )
+      )
+      ShortDeclaration(
+        Var( name: d varType: IntType )
+        When(
+          type: IntType
+          case: _ Boc( BasicLit( tt: int value: 6 basicType: IntType ) )
+        )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid indexes",
+			source: "a: [1]\nb: a[\"x\"]\nd: [\"k\": 1]\ne: d[1]\nf: 1[0]\na[0] = \"s\"\ng: a[]",
+			wantErrors: []string{
+				"[recovery: line: 2 col: 6] expected an Int index. Got String",
+				"[recovery: line: 4 col: 6] expected a String key. Got Int",
+				"[recovery: line: 5 col: 4] cannot index a value of type Int\nHint: only the arrays and the dictionaries have elements",
+				"[recovery: line: 6 col: 8] cannot use String value as Int in the element of a",
+				"[recovery: line: 7 col: 6] expected an index after \"[\". Got \"]\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: a varType: ArrayType(IntType) )
+        ArrayLit( arrayType: ArrayType(IntType) expressions: [ BasicLit( tt: int value: 1 basicType: IntType ) ] )
+      )
+      ShortDeclaration(
+        Var( name: b varType: IntType )
+        Index(
+          type: IntType
+          receiver: Var( name: a varType: ArrayType(IntType) )
+          index: BasicLit( tt: str value: x basicType: StringType )
+        )
+      )
+      ShortDeclaration(
+        Var( name: d varType: DictType( key: StringType value: IntType) )
+        DictLit(
+          dictType: DictType( key: StringType value: IntType )
+          keys: [ BasicLit( tt: str value: k basicType: StringType ) ]
+          values: [ BasicLit( tt: int value: 1 basicType: IntType ) ]
+        )
+      )
+      ShortDeclaration(
+        Var( name: e varType: VariantType(Option) )
+        Index(
+          type: VariantType(Option)
+          receiver: Var( name: d varType: DictType( key: StringType value: IntType) )
+          index: BasicLit( tt: int value: 1 basicType: IntType )
+        )
+      )
+      ShortDeclaration(
+        Var( name: f varType: TBD )
+        Index(
+          type: TBD
+          receiver: BasicLit( tt: int value: 1 basicType: IntType )
+          index: BasicLit( tt: int value: 0 basicType: IntType )
+        )
+      )
+      IndexAssignment(
+        Index(
+          type: IntType
+          receiver: Var( name: a varType: ArrayType(IntType) )
+          index: BasicLit( tt: int value: 0 basicType: IntType )
+        )
+        value: BasicLit( tt: str value: s basicType: StringType )
+      )
+      BadExpr
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid match",
+			source: "a: 1 match 2\nb: a match { 1 => 2 }, { Int => 3 }\nc: a match { Int => 1 }, { _ => \"one\" }\nd: a match { Int 2 => 5 }, { _ => 6 }",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 12] expected a case after \"match\" like { Type => value }. Got \"2\"",
+				"[recovery: line: 2 col: 14] expected a type or \"_\". Got \"1\"",
+				"[recovery: line: 3 col: 30] the cases of match have different types: Int and String",
+				"[recovery: line: 4 col: 18] expected \"=>\" after the type. Got \"2\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      BadExpr
+      ShortDeclaration(
+        Var( name: b varType: IntType )
+        Match(
+          type: IntType
+          Var( name: a varType: TBD )
+          case: Int: IntType Boc( BasicLit( tt: int value: 3 basicType: IntType ) )
+        )
+      )
+      ShortDeclaration(
+        Var( name: c varType: TBD )
+        Match(
+          type: TBD
+          Var( name: a varType: TBD )
+          case: Int: IntType Boc( BasicLit( tt: int value: 1 basicType: IntType ) )
+          case: _ Boc( BasicLit( tt: str value: one basicType: StringType ) )
+        )
+      )
+      ShortDeclaration(
+        Var( name: d varType: IntType )
+        Match(
+          type: IntType
+          Var( name: a varType: TBD )
+          case: _ Boc( BasicLit( tt: int value: 6 basicType: IntType ) )
+        )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid instantiations",
+			source: "Point #(x Int, y Int)\na: Point(1, 2, 3)\nb: Point(x: 1, z: 2)\nc: Point(\"one\", 2)",
+			wantErrors: []string{
+				"[recovery: line: 2 col: 16] too many arguments for Point, it has 2 members",
+				"[recovery: line: 3 col: 16] Point has no member \"z\"",
+				"[recovery: line: 3 col: 4] missing the value of y in Point\nHint: pass a value for each member without a default value",
+				"[recovery: line: 4 col: 10] cannot use String value as Int in the member x of Point",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: a varType: BocType(Point) )
+        TypeInstantiation(
+          type: BocType(Point)
+          values: [
+            NamedArg( name: x BasicLit( tt: int value: 1 basicType: IntType ) )
+            NamedArg( name: y BasicLit( tt: int value: 2 basicType: IntType ) )
+          ]
+          defaults: [ ]
+        )
+      )
+      ShortDeclaration(
+        Var( name: b varType: BocType(Point) )
+        TypeInstantiation(
+          type: BocType(Point)
+          values: [ NamedArg( name: x BasicLit( tt: int value: 1 basicType: IntType ) ) ]
+          defaults: [ ]
+        )
+      )
+      ShortDeclaration(
+        Var( name: c varType: BocType(Point) )
+        TypeInstantiation(
+          type: BocType(Point)
+          values: [
+            NamedArg( name: x BasicLit( tt: str value: one basicType: StringType ) )
+            NamedArg( name: y BasicLit( tt: int value: 2 basicType: IntType ) )
+          ]
+          defaults: [ ]
+        )
+      )
+      TypeDeclaration(
+        name: Point
+        variables: [ Var( name: x varType: IntType ) Var( name: y varType: IntType ) ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Unknown member",
+			source: "p: {x: 1}\na: p.x\nb: p.y",
+			wantErrors: []string{
+				"[recovery: line: 3 col: 6] #(x Int, Int) has no member \"y\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: p varType: BocType )
+        Boc(
+          ShortDeclaration(
+            Var( name: x varType: IntType )
+            BasicLit( tt: int value: 1 basicType: IntType )
+          )
+        )
+      )
+      ShortDeclaration(
+        Var( name: a varType: IntType )
+        MemberAccess(
+          receiver: Var( name: p varType: BocType )
+          member: Var( name: x varType: IntType )
+        )
+      )
+      ShortDeclaration(
+        Var( name: b varType: TBD )
+        MemberAccess(
+          receiver: Var( name: p varType: BocType )
+          member: Var( name: y varType: TBD )
+        )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid generics",
+			source: "Option { Some(value T), None() }\na Option = None()\nb Option(Int, String) = None()\nc []U\nf #(T, T, x T)",
+			wantErrors: []string{
+				"[recovery: line: 2 col: 3] missing the type arguments of Option\nHint: pass the types its type parameters take, like Option(Int)",
+				"[recovery: line: 3 col: 3] wrong number of type arguments for Option, want 1",
+				"[recovery: line: 4 col: 5] the type parameter U is not declared\nHint: declare it in a block signature like #(T, x T) or use it in a type declaration",
+				"[recovery: line: 5 col: 8] duplicate type parameter \"T\"",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      VariantDeclaration(
+        name: Option
+        cases: [
+          Some( Var( name: value varType: GenericType(T) ) )
+          None( )
+        ]
+      )
+      VariableDeclaration(
+        variables: [ Var( name: a varType: VariantType(Option) ) ]
+        values: [ TypeInstantiation( type: BocType(None) values: [ ] defaults: [ ] ) ]
+      )
+      VariableDeclaration(
+        variables: [ Var( name: b varType: VariantType(Option) ) ]
+        values: [ TypeInstantiation( type: BocType(None) values: [ ] defaults: [ ] ) ]
+      )
+      VariableDeclaration(
+        variables: [ Var( name: c varType: ArrayType(TBD) ) ]
+        values: [ ]
+      )
+      VariableDeclaration(
+        variables: [ Var( name: f varType: BocType ) ]
+        values: [ ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Other type arguments in the own declaration",
+			source: "List { Cons(head T, tail List(Int)), Empty() }",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 26] List takes its own type parameters in its declaration, like List(T)",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      VariantDeclaration(
+        name: List
+        cases: [
+          Cons( Var( name: head varType: GenericType(T) ) Var( name: tail varType: VariantType(List) ) )
+          Empty( )
+        ]
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Invalid interpolations",
+			source: "n: 1\na: \"`n n` and `{ n }` and `(n, n)`\"\nf: { n }\nb: '`f`'",
+			wantErrors: []string{
+				"[recovery: line: 2 col: 8] expected \"`\" after the expression of the placeholder. Got \"n\"",
+				"[recovery: line: 2 col: 16] cannot write a block in a string\nHint: invoke it to write its result",
+				"[recovery: line: 2 col: 28] cannot write a tuple in a string\nHint: write each value in its own placeholder",
+				"[recovery: line: 4 col: 6] cannot write a block in a string\nHint: invoke it to write its result, like `f()`",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: n varType: IntType )
+        BasicLit( tt: int value: 1 basicType: IntType )
+      )
+      ShortDeclaration(
+        Var( name: a varType: StringType )
+        InterpolatedString(
+          part: ""
+          BadExpr
+          part: " and "
+          Boc( Var( name: n varType: IntType ) )
+          part: " and "
+          ParenthesisExp(
+            type: TupleType(IntType, IntType)
+            expressions: [ Var( name: n varType: IntType ) Var( name: n varType: IntType ) ]
+          )
+          part: ""
+        )
+      )
+      ShortDeclaration(
+        Var( name: f varType: BocType )
+        Boc( Var( name: n varType: IntType ) )
+      )
+      ShortDeclaration(
+        Var( name: b varType: StringType )
+        InterpolatedString( part: "" Var( name: f varType: BocType ) part: "" )
+      )
+    )
+  )
+)`,
+		},
+		{
+			name:   "Elements of different types",
+			source: "a: [1, \"x\"]\nd: [\"a\": 1, \"b\": \"x\"]\ne: [1: \"a\", \"b\": \"c\"]",
+			wantErrors: []string{
+				"[recovery: line: 1 col: 8] the elements of the array have different types: Int and String\nHint: write values of the same type",
+				"[recovery: line: 2 col: 18] the values of the dictionary have different types: Int and String\nHint: write values of the same type",
+				"[recovery: line: 3 col: 13] the keys of the dictionary have different types: Int and String\nHint: write values of the same type",
+			},
+			wantAst: `Boc(
+  ShortDeclaration(
+    Var( name: recovery varType: BocType )
+    Boc(
+      ShortDeclaration(
+        Var( name: a varType: ArrayType(IntType) )
+        ArrayLit(
+          arrayType: ArrayType(IntType)
+          expressions: [ BasicLit( tt: int value: 1 basicType: IntType ) BasicLit( tt: str value: x basicType: StringType ) ]
+        )
+      )
+      ShortDeclaration(
+        Var( name: d varType: DictType( key: StringType value: IntType ) )
+        DictLit(
+          dictType: DictType( key: StringType value: IntType )
+          keys: [ BasicLit( tt: str value: a basicType: StringType ) BasicLit( tt: str value: b basicType: StringType ) ]
+          values: [ BasicLit( tt: int value: 1 basicType: IntType ) BasicLit( tt: str value: x basicType: StringType ) ]
+        )
+      )
+      ShortDeclaration(
+        Var( name: e varType: DictType( key: IntType value: StringType ) )
+        DictLit(
+          dictType: DictType( key: IntType value: StringType )
+          keys: [ BasicLit( tt: int value: 1 basicType: IntType ) BasicLit( tt: str value: b basicType: StringType ) ]
+          values: [ BasicLit( tt: str value: a basicType: StringType ) BasicLit( tt: str value: c basicType: StringType ) ]
+        )
+      )
+    )
+  )
+)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := &fileSet{}
+			tokens, err := Tokenize(files, []string{"recovery"}, tt.source)
+			if err != nil {
+				t.Errorf("Tokenize() error = \"%v\"", err)
+				return
+			}
+			got, err := Parse(files, []string{"recovery"}, tokens)
+			if got == nil {
+				t.Errorf("Parse() returned a nil AST")
+				return
+			}
+			if err == nil {
+				t.Errorf("Parse() error = nil, want %v", tt.wantErrors)
+				return
+			}
+			if err.Error() != strings.Join(tt.wantErrors, "\n") {
+				t.Errorf("Parse() error = \n%v\nwant\n%v", err, strings.Join(tt.wantErrors, "\n"))
+			}
+			if removeSpaces(got.String()) != removeSpaces(tt.wantAst) {
+				t.Errorf("Parse() = \n%v\nwant\n%v", got, tt.wantAst)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -32,6 +33,15 @@ func prettyPrint(v interface{}, indent int) string {
 		sb.WriteString(indentStr(indent+2) + "value: " + v.val + "\n")
 		sb.WriteString(indentStr(indent+2) + "basicType: " + prettyPrint(v.basicType, 0) + "\n")
 		sb.WriteString(indentStr(indent) + ")\n")
+	case *InterpolatedString:
+		sb.WriteString(indentStr(indent) + "InterpolatedString(\n")
+		for i, part := range v.parts {
+			sb.WriteString(indentStr(indent+2) + "part: " + strconv.Quote(part) + "\n")
+			if i < len(v.values) {
+				sb.WriteString(prettyPrint(v.values[i], indent+2))
+			}
+		}
+		sb.WriteString(indentStr(indent) + ")\n")
 	case *ArrayLit:
 		sb.WriteString(indentStr(indent) + "ArrayLit(\n")
 		//sb.WriteString(indentStr(indent+2) + "pos: " + v.pos.String() + "\n")
@@ -76,6 +86,158 @@ func prettyPrint(v interface{}, indent int) string {
 		sb.WriteString(indentStr(indent+2) + "name: " + v.name + "\n")
 		sb.WriteString(indentStr(indent+2) + "varType: " + prettyPrint(v.varType, 0) + "\n")
 		sb.WriteString(indentStr(indent) + ")\n")
+	case *Invocation:
+		sb.WriteString(indentStr(indent) + "Invocation(\n")
+		sb.WriteString(indentStr(indent+2) + "callee:\n" + prettyPrint(v.callee, indent+4))
+		sb.WriteString(indentStr(indent+2) + "args: [\n")
+		for _, arg := range v.args {
+			sb.WriteString(prettyPrint(arg, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent+2) + "namedArgs: [\n")
+		for _, arg := range v.namedArgs {
+			sb.WriteString(prettyPrint(arg, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *MemberAccess:
+		sb.WriteString(indentStr(indent) + "MemberAccess(\n")
+		sb.WriteString(indentStr(indent+2) + "receiver:\n" + prettyPrint(v.receiver, indent+4))
+		sb.WriteString(indentStr(indent+2) + "member:\n" + prettyPrint(v.member, indent+4))
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *TypeInstantiation:
+		sb.WriteString(indentStr(indent) + "TypeInstantiation(\n")
+		sb.WriteString(indentStr(indent+2) + "type: " + strings.TrimSpace(prettyPrint(v.instType, 0)) + "\n")
+		sb.WriteString(indentStr(indent+2) + "values: [\n")
+		for _, arg := range v.values {
+			sb.WriteString(prettyPrint(arg, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent+2) + "defaults: [\n")
+		for _, arg := range v.defaults {
+			sb.WriteString(prettyPrint(arg, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *NamedArg:
+		sb.WriteString(indentStr(indent) + "NamedArg(\n")
+		sb.WriteString(indentStr(indent+2) + "name: " + v.name + "\n")
+		sb.WriteString(prettyPrint(v.value, indent+2))
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *Assignment:
+		sb.WriteString(indentStr(indent) + "Assignment(\n")
+		sb.WriteString(indentStr(indent+2) + "variables: [\n")
+		for _, v := range v.variables {
+			sb.WriteString(prettyPrint(v, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent+2) + "values: [\n")
+		for _, exp := range v.values {
+			sb.WriteString(prettyPrint(exp, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *VariableDeclaration:
+		sb.WriteString(indentStr(indent) + "VariableDeclaration(\n")
+		sb.WriteString(indentStr(indent+2) + "variables: [\n")
+		for _, v := range v.variables {
+			sb.WriteString(prettyPrint(v, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent+2) + "values: [\n")
+		for _, exp := range v.values {
+			sb.WriteString(prettyPrint(exp, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *TypeDeclaration:
+		sb.WriteString(indentStr(indent) + "TypeDeclaration(\n")
+		sb.WriteString(indentStr(indent+2) + "name: " + v.variable.name + "\n")
+		sb.WriteString(indentStr(indent+2) + "variables: [\n")
+		for _, member := range v.variable.varType.(*BocType).variables {
+			sb.WriteString(prettyPrint(member, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		if v.body != nil {
+			sb.WriteString(prettyPrint(v.body, indent+2))
+		}
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *VariantDeclaration:
+		sb.WriteString(indentStr(indent) + "VariantDeclaration(\n")
+		sb.WriteString(indentStr(indent+2) + "name: " + v.variable.name + "\n")
+		sb.WriteString(indentStr(indent+2) + "cases: [\n")
+		for _, c := range v.cases {
+			sb.WriteString(indentStr(indent+4) + c.name + "(\n")
+			for _, member := range c.varType.(*BocType).variables {
+				sb.WriteString(prettyPrint(member, indent+6))
+			}
+			sb.WriteString(indentStr(indent+4) + ")\n")
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *When:
+		sb.WriteString(indentStr(indent) + "When(\n")
+		sb.WriteString(indentStr(indent+2) + "type: " + prettyPrint(v.whenType, 0) + "\n")
+		for _, c := range v.cases {
+			sb.WriteString(indentStr(indent+2) + "case:\n")
+			if c.condition == nil {
+				sb.WriteString(indentStr(indent+4) + "_\n")
+			} else {
+				sb.WriteString(prettyPrint(c.condition, indent+4))
+			}
+			sb.WriteString(prettyPrint(c.body, indent+4))
+		}
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *Index:
+		sb.WriteString(indentStr(indent) + "Index(\n")
+		sb.WriteString(indentStr(indent+2) + "type: " + strings.TrimSpace(prettyPrint(v.indexType, 0)) + "\n")
+		sb.WriteString(indentStr(indent+2) + "receiver:\n" + prettyPrint(v.receiver, indent+4))
+		sb.WriteString(indentStr(indent+2) + "index:\n" + prettyPrint(v.index, indent+4))
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *IndexAssignment:
+		sb.WriteString(indentStr(indent) + "IndexAssignment(\n")
+		sb.WriteString(prettyPrint(v.target, indent+2))
+		sb.WriteString(indentStr(indent+2) + "value:\n" + prettyPrint(v.value, indent+4))
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *Match:
+		sb.WriteString(indentStr(indent) + "Match(\n")
+		sb.WriteString(indentStr(indent+2) + "type: " + prettyPrint(v.matchType, 0) + "\n")
+		sb.WriteString(prettyPrint(v.value, indent+2))
+		for _, c := range v.cases {
+			sb.WriteString(indentStr(indent+2) + "case:\n")
+			if c.pattern == nil {
+				sb.WriteString(indentStr(indent+4) + "_\n")
+			} else {
+				sb.WriteString(indentStr(indent+4) + c.pattern.name + ": " + strings.TrimSpace(prettyPrint(c.pattern.varType, 0)) + "\n")
+			}
+			sb.WriteString(prettyPrint(c.body, indent+4))
+		}
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *ParenthesisExp:
+		sb.WriteString(indentStr(indent) + "ParenthesisExp(\n")
+		sb.WriteString(indentStr(indent+2) + "type: " + prettyPrint(v.dataType(), 0) + "\n")
+		sb.WriteString(indentStr(indent+2) + "expressions: [\n")
+		for _, exp := range v.expressions {
+			sb.WriteString(prettyPrint(exp, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *BadExpr:
+		sb.WriteString(indentStr(indent) + "BadExpr\n")
+	case *Return:
+		sb.WriteString(indentStr(indent) + "Return(\n")
+		sb.WriteString(indentStr(indent+2) + "values: [\n")
+		for _, exp := range v.values {
+			sb.WriteString(prettyPrint(exp, indent+4))
+		}
+		sb.WriteString(indentStr(indent+2) + "]\n")
+		sb.WriteString(indentStr(indent) + ")\n")
+	case *Break:
+		sb.WriteString(indentStr(indent) + "Break\n")
+	case *Continue:
+		sb.WriteString(indentStr(indent) + "Continue\n")
+	case *BadStmt:
+		sb.WriteString(indentStr(indent) + "BadStmt\n")
 		// Types
 	case *IntType:
 		sb.WriteString(indentStr(indent) + "IntType")
@@ -83,6 +245,8 @@ func prettyPrint(v interface{}, indent int) string {
 		sb.WriteString(indentStr(indent) + "DecimalType")
 	case *StringType:
 		sb.WriteString(indentStr(indent) + "StringType")
+	case *BoolType:
+		sb.WriteString(indentStr(indent) + "BoolType")
 	case *ArrayType:
 		sb.WriteString(indentStr(indent) + "ArrayType(")
 		sb.WriteString(prettyPrint(v.elemType, 0))
@@ -94,6 +258,22 @@ func prettyPrint(v interface{}, indent int) string {
 		sb.WriteString(indentStr(indent) + ")")
 	case *BocType:
 		sb.WriteString(indentStr(indent) + "BocType")
+		if v.name != "" {
+			sb.WriteString("(" + v.String() + ")")
+		}
+	case *TupleType:
+		sb.WriteString(indentStr(indent) + "TupleType(")
+		for i, t := range v.elemTypes {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(strings.TrimSpace(prettyPrint(t, 0)))
+		}
+		sb.WriteString(")")
+	case *VariantType:
+		sb.WriteString(indentStr(indent) + "VariantType(" + v.String() + ")")
+	case *GenericType:
+		sb.WriteString(indentStr(indent) + "GenericType(" + v.name + ")")
 	case *TBD:
 		sb.WriteString(indentStr(indent) + "TBD\n")
 	// Add more cases for other types as needed
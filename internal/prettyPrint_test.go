@@ -16,7 +16,7 @@ func TestPrettyPrint(t *testing.T) {
 			input: &Boc{
 				expressions: []expression{
 					&BasicLit{
-						pos:       pos(1, 1),
+						span:      sp(0, 7),
 						tt:        STRING,
 						val:       "Hello",
 						basicType: &StringType{},
@@ -36,7 +36,7 @@ func TestPrettyPrint(t *testing.T) {
 		{
 			name: "BasicLit",
 			input: &BasicLit{
-				pos:       pos(1, 1),
+				span:      sp(0, 7),
 				tt:        STRING,
 				val:       "Hello",
 				basicType: &StringType{},
@@ -50,13 +50,13 @@ func TestPrettyPrint(t *testing.T) {
 		{
 			name: "ArrayLit",
 			input: &ArrayLit{
-				pos: pos(1, 1),
+				span: sp(0, 4),
 				arrayType: &ArrayType{
 					elemType: &IntType{},
 				},
 				expressions: []expression{
 					&BasicLit{
-						pos:       pos(2, 1),
+						span:      sp(2, 3),
 						tt:        INTEGER,
 						val:       "1",
 						basicType: &IntType{},
@@ -72,6 +72,32 @@ func TestPrettyPrint(t *testing.T) {
                     basicType: IntType
                 )
             ]
+)`,
+		},
+		{
+			name: "ParenthesisExp",
+			input: &ParenthesisExp{
+				lparen: sp(0, 1),
+				expressions: []expression{
+					&BasicLit{span: sp(1, 2), tt: INTEGER, val: "1", basicType: &IntType{}},
+					&BasicLit{span: sp(4, 7), tt: STRING, val: "a", basicType: &StringType{}},
+				},
+				rparen: sp(7, 8),
+			},
+			expected: `ParenthesisExp(
+            type: TupleType(IntType, StringType)
+            expressions: [
+                BasicLit(
+                    tt: int
+                    value: 1
+                    basicType: IntType
+                )
+                BasicLit(
+                    tt: str
+                    value: a
+                    basicType: StringType
+                )
+            ]
 )`,
 		},
 	}
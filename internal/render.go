@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI styles used when rendering with color.
+const (
+	styleBold   = "1"
+	styleRed    = "1;31"
+	styleYellow = "1;33"
+	styleCyan   = "1;36"
+	styleBlue   = "1;34"
+)
+
+// tabWidth is the number of columns a tab takes in a rendered source line.
+const tabWidth = 4
+
+// maxSpanLines is the number of lines of a multi-line span shown before the rest are elided.
+const maxSpanLines = 3
+
+// Render returns the diagnostic formatted for the user: a header with the severity, code and message,
+// the location, the source lines of the span and its labels with the columns underlined, and the hint.
+// When color is true the output has ANSI escape sequences.
+//
+//	error[E0200]: expected "}". Got "EOF"
+//	 --> main.yz:4:1
+//	  |
+//	1 | main: {
+//	  |       - the block starts here
+//	...
+//	4 |
+//	  | ^
+func (d *Diagnostic) Render(color bool) string {
+	r := &renderer{color: color, style: severityStyle(d.Severity), gutter: 1}
+	r.header(d)
+	r.excerpts(d)
+	if d.Hint != "" {
+		r.hint(d.Hint)
+	}
+	return r.sb.String()
+}
+
+// Render returns every diagnostic rendered with Diagnostic.Render, separated by empty lines.
+func (ds *Diagnostics) Render(color bool) string {
+	rendered := make([]string, len(ds.list))
+	for i, d := range ds.list {
+		rendered[i] = d.Render(color)
+	}
+	return strings.Join(rendered, "\n")
+}
+
+type renderer struct {
+	sb     strings.Builder
+	color  bool
+	style  string // style of the severity, used for the header and the primary underline
+	gutter int    // width of the line numbers column
+}
+
+// mark is an underlined range of columns in a source line, from and to are inclusive.
+type mark struct {
+	line     int
+	from, to int
+	primary  bool
+	message  string
+}
+
+func (r *renderer) paint(style, s string) string {
+	if !r.color || s == "" {
+		return s
+	}
+	return "\x1b[" + style + "m" + s + "\x1b[0m"
+}
+
+func severityStyle(s Severity) string {
+	switch s {
+	case SeverityError:
+		return styleRed
+	case SeverityWarning:
+		return styleYellow
+	default:
+		return styleCyan
+	}
+}
+
+func (r *renderer) header(d *Diagnostic) {
+	title := d.Severity.String()
+	if d.Code != "" {
+		title += "[" + d.Code + "]"
+	}
+	r.sb.WriteString(r.paint(r.style, title))
+	r.sb.WriteString(r.paint(styleBold, ": "+d.Message))
+	r.sb.WriteString("\n")
+}
+
+// excerpts writes the source lines of the diagnostic span followed by those of the labels.
+// Labels in another file get their own location line.
+func (r *renderer) excerpts(d *Diagnostic) {
+	file := d.fileName()
+	source := d.sources.file(d.span.file)
+	if source == nil {
+		if file != "" {
+			r.location(file, position{})
+		}
+		return
+	}
+
+	byFile := map[fileID][]mark{}
+	order := []fileID{d.span.file}
+	byFile[d.span.file] = marks(source, d.span, true, "")
+	for _, l := range d.labels {
+		labelSource := d.sources.file(l.span.file)
+		if labelSource == nil {
+			continue
+		}
+		if _, ok := byFile[l.span.file]; !ok {
+			order = append(order, l.span.file)
+		}
+		byFile[l.span.file] = append(byFile[l.span.file], marks(labelSource, l.span, false, l.message)...)
+	}
+
+	for _, id := range order {
+		for _, m := range byFile[id] {
+			r.gutter = max(r.gutter, len(strconv.Itoa(m.line)))
+		}
+	}
+	for i, id := range order {
+		if i == 0 {
+			r.location(file, d.sources.position(d.span))
+		} else {
+			r.location(d.sources.file(id).name, byFile[id][0].position())
+		}
+		r.lines(d.sources.file(id), byFile[id])
+	}
+}
+
+func (r *renderer) location(file string, p position) {
+	arrow := strings.Repeat(" ", r.gutter) + "--> "
+	if p == (position{}) {
+		r.sb.WriteString(r.paint(styleBlue, arrow) + file + "\n")
+		return
+	}
+	r.sb.WriteString(fmt.Sprintf("%s%s:%d:%d\n", r.paint(styleBlue, arrow), file, p.line, p.col))
+}
+
+// lines writes every line with a mark, the marks under it and "..." where lines are skipped.
+func (r *renderer) lines(source *sourceText, marks []mark) {
+	sort.SliceStable(marks, func(i, j int) bool {
+		if marks[i].line != marks[j].line {
+			return marks[i].line < marks[j].line
+		}
+		return marks[i].primary && !marks[j].primary
+	})
+	r.sb.WriteString(r.paint(styleBlue, strings.Repeat(" ", r.gutter+1)+"|") + "\n")
+	previous := 0
+	for _, m := range marks {
+		if m.line != previous {
+			if previous != 0 && m.line > previous+1 {
+				r.sb.WriteString(r.paint(styleBlue, "...") + "\n")
+			}
+			text := source.line(m.line)
+			number := fmt.Sprintf("%*d |", r.gutter, m.line)
+			r.sb.WriteString(strings.TrimRight(r.paint(styleBlue, number)+" "+expandTabs(text), " ") + "\n")
+			previous = m.line
+		}
+		r.underline(source.line(m.line), m)
+	}
+}
+
+func (r *renderer) underline(text string, m mark) {
+	from := displayColumn(text, m.from)
+	width := max(displayColumn(text, m.to+1)-from, 1)
+	symbol, style := "^", r.style
+	if !m.primary {
+		symbol, style = "-", styleBlue
+	}
+	line := strings.Repeat(" ", from) + r.paint(style, strings.Repeat(symbol, width))
+	if m.message != "" {
+		line += " " + r.paint(style, m.message)
+	}
+	r.sb.WriteString(r.paint(styleBlue, strings.Repeat(" ", r.gutter+1)+"|") + " " + line + "\n")
+}
+
+func (r *renderer) hint(hint string) {
+	r.sb.WriteString(strings.Repeat(" ", r.gutter+1) + r.paint(styleBold, "= hint:") + " " + hint + "\n")
+}
+
+// marks returns a mark for each line the span covers. Spans longer than maxSpanLines only mark
+// their first and last lines.
+func marks(source *sourceText, s span, primary bool, message string) []mark {
+	start := source.position(s.start)
+	last := start
+	if s.end > s.start {
+		// the position of the last byte of the span, which is inclusive
+		last = source.position(s.end - 1)
+	}
+	var result []mark
+	for line := start.line; line <= last.line; line++ {
+		if last.line-start.line >= maxSpanLines && line != start.line && line != last.line {
+			continue
+		}
+		from, to := 1, utf8.RuneCountInString(source.line(line))
+		if line == start.line {
+			from = start.col
+		}
+		if line == last.line {
+			to = last.col
+		}
+		result = append(result, mark{line, from, max(to, from), primary, ""})
+	}
+	// the message goes under the last line of the span
+	result[len(result)-1].message = message
+	return result
+}
+
+func (m mark) position() position {
+	return position{m.line, m.from}
+}
+
+// displayColumn returns the 0-based column where the rune at the 1-based col is shown once the tabs
+// are expanded. Columns past the end of the text are counted as one column each.
+func displayColumn(text string, col int) int {
+	display := 0
+	for _, r := range text {
+		if col <= 1 {
+			return display
+		}
+		if r == '\t' {
+			display += tabWidth
+		} else {
+			display++
+		}
+		col--
+	}
+	return display + max(col-1, 0)
+}
+
+func expandTabs(text string) string {
+	return strings.ReplaceAll(text, "\t", strings.Repeat(" ", tabWidth))
+}
@@ -0,0 +1,98 @@
+package internal
+
+import "testing"
+
+func TestDiagnostic_Render(t *testing.T) {
+	files := &fileSet{}
+	main := files.add("main.yz", "main: {\n  a: 1\n  b: [1, 2\n")
+	str := files.add("str.yz", "a: \"open\nmore\nlines\nhere\nend")
+	tab := files.add("tab.yz", "\tx: `")
+	tests := []struct {
+		name       string
+		diagnostic *Diagnostic
+		color      bool
+		want       string
+	}{
+		{
+			"Caret under the span",
+			newDiagnostic(span{tab, 4, 5}, codeLexical, "unexpected character `"),
+			false,
+			"error[E0100]: unexpected character `\n" +
+				" --> tab.yz:1:5\n" +
+				"  |\n" +
+				"1 |     x: `\n" +
+				"  |        ^\n",
+		},
+		{
+			"Secondary label on an earlier line",
+			newDiagnostic(span{main, 26, 26}, codeSyntax, "expected \"}\". Got \"EOF\"").
+				withLabel(span{main, 6, 7}, "the block starts here"),
+			false,
+			"error[E0200]: expected \"}\". Got \"EOF\"\n" +
+				" --> main.yz:4:1\n" +
+				"  |\n" +
+				"1 | main: {\n" +
+				"  |       - the block starts here\n" +
+				"...\n" +
+				"4 |\n" +
+				"  | ^\n",
+		},
+		{
+			"Secondary label on the same line",
+			newDiagnostic(span{main, 24, 25}, codeSyntax, "expected \",\" or \"]\". Got \"2\"").
+				withLabel(span{main, 20, 21}, "the literal starts here"),
+			false,
+			"error[E0200]: expected \",\" or \"]\". Got \"2\"\n" +
+				" --> main.yz:3:10\n" +
+				"  |\n" +
+				"3 |   b: [1, 2\n" +
+				"  |          ^\n" +
+				"  |      - the literal starts here\n",
+		},
+		{
+			"Long spans show their first and last lines and the hint",
+			newDiagnostic(span{str, 3, 28}, codeLexical, "unterminated string literal").withHint("close the string with \""),
+			false,
+			"error[E0100]: unterminated string literal\n" +
+				" --> str.yz:1:4\n" +
+				"  |\n" +
+				"1 | a: \"open\n" +
+				"  |    ^^^^^\n" +
+				"...\n" +
+				"5 | end\n" +
+				"  | ^^^\n" +
+				"  = hint: close the string with \"\n",
+		},
+		{
+			"File without span",
+			&Diagnostic{File: "gone.yz", Code: codeRead, Message: "no such file or directory"},
+			false,
+			"error[E0001]: no such file or directory\n" +
+				" --> gone.yz\n",
+		},
+		{
+			"Warning without file",
+			&Diagnostic{Severity: SeverityWarning, Message: "nothing to do"},
+			false,
+			"warning: nothing to do\n",
+		},
+		{
+			"Color",
+			newDiagnostic(span{tab, 4, 5}, codeLexical, "unexpected character `"),
+			true,
+			"\x1b[1;31merror[E0100]\x1b[0m\x1b[1m: unexpected character `\x1b[0m\n" +
+				"\x1b[1;34m --> \x1b[0mtab.yz:1:5\n" +
+				"\x1b[1;34m  |\x1b[0m\n" +
+				"\x1b[1;34m1 |\x1b[0m     x: `\n" +
+				"\x1b[1;34m  |\x1b[0m        \x1b[1;31m^\x1b[0m\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			(&Diagnostics{sources: files}).Add(tt.diagnostic)
+			if got := tt.diagnostic.Render(tt.color); got != tt.want {
+				t.Errorf("Render() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
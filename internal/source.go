@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// fileID identifies a file registered in a fileSet.
+// The zero fileID means no file, it is used by the nodes the compiler creates itself, like the parents of a file.
+type fileID int
+
+// span is the range of bytes [start, end) of a source file.
+// The line and column are derived from the file's line table when needed.
+type span struct {
+	file  fileID
+	start int
+	end   int
+}
+
+// position is the human-readable location of a span: 1-based line and column, the column counted in runes.
+type position struct {
+	line int
+	col  int
+}
+
+// sourceText is a file registered in a fileSet.
+type sourceText struct {
+	name    string
+	content string
+	lines   []int // byte offset where each line starts
+}
+
+// fileSet holds the source files of a build or a check so the spans of their tokens and AST nodes
+// can be resolved to file names, lines and columns. Each build or check has its own fileSet, the
+// spans are only meaningful in the fileSet of the files they come from.
+type fileSet struct {
+	files []*sourceText
+}
+
+// add registers a file and returns its fileID.
+func (fs *fileSet) add(name, content string) fileID {
+	lines := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	fs.files = append(fs.files, &sourceText{name, content, lines})
+	return fileID(len(fs.files))
+}
+
+// file returns the registered file or nil if the id is unknown or there is no fileSet.
+func (fs *fileSet) file(id fileID) *sourceText {
+	if fs == nil || id <= 0 || int(id) > len(fs.files) {
+		return nil
+	}
+	return fs.files[id-1]
+}
+
+// position returns the line and column of a byte offset.
+func (st *sourceText) position(offset int) position {
+	offset = min(max(offset, 0), len(st.content))
+	line := sort.Search(len(st.lines), func(i int) bool { return st.lines[i] > offset })
+	lineStart := st.lines[line-1]
+	return position{line, utf8.RuneCountInString(st.content[lineStart:offset]) + 1}
+}
+
+// line returns the text of a 1-based line without the line terminator.
+func (st *sourceText) line(n int) string {
+	if n < 1 || n > len(st.lines) {
+		return ""
+	}
+	end := len(st.content)
+	if n < len(st.lines) {
+		end = st.lines[n] - 1
+	}
+	return strings.TrimSuffix(st.content[st.lines[n-1]:end], "\r")
+}
+
+// position returns where the span starts, or the zero position if its file is unknown.
+func (fs *fileSet) position(s span) position {
+	if f := fs.file(s.file); f != nil {
+		return f.position(s.start)
+	}
+	return position{}
+}
+
+// endPosition returns the position just after the last byte of the span.
+func (fs *fileSet) endPosition(s span) position {
+	if f := fs.file(s.file); f != nil {
+		return f.position(s.end)
+	}
+	return position{}
+}
+
+// fileName returns the name of the file the span belongs to, or "" if it's unknown.
+func (fs *fileSet) fileName(s span) string {
+	if f := fs.file(s.file); f != nil {
+		return f.name
+	}
+	return ""
+}
+
+// to returns a span from the start of s to the end of other.
+func (s span) to(other span) span {
+	return span{s.file, s.start, other.end}
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("line: %d col: %d", p.line, p.col)
+}
@@ -0,0 +1,53 @@
+package internal
+
+import "testing"
+
+func TestSourceText_Position(t *testing.T) {
+	files := &fileSet{}
+	file := files.add("a.yz", "a: 1\nñu: \"x\"\r\n\nb")
+	tests := []struct {
+		name string
+		span span
+		want position
+		line string
+	}{
+		{"Start of file", span{file, 0, 1}, position{1, 1}, "a: 1"},
+		{"Same line", span{file, 3, 4}, position{1, 4}, "a: 1"},
+		{"Columns count runes", span{file, 8, 9}, position{2, 3}, "ñu: \"x\""},
+		{"Empty line", span{file, 15, 15}, position{3, 1}, ""},
+		{"Last line", span{file, 16, 17}, position{4, 1}, "b"},
+		{"End of file", span{file, 17, 17}, position{4, 2}, "b"},
+		{"Unknown file", span{0, 3, 4}, position{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := files.position(tt.span)
+			if got != tt.want {
+				t.Errorf("position() = %v, want %v", got, tt.want)
+			}
+			if f := files.file(tt.span.file); f != nil {
+				if line := f.line(got.line); line != tt.line {
+					t.Errorf("line(%d) = %q, want %q", got.line, line, tt.line)
+				}
+			}
+		})
+	}
+}
+
+func TestFileSet_SpansOfEachBuild(t *testing.T) {
+	// every build registers its files in its own fileSet, so the same fileID is a different file in each one
+	first, second := &fileSet{}, &fileSet{}
+	a := first.add("a.yz", "a: 1")
+	b := second.add("b.yz", "\n\nb: 2")
+	if a != b {
+		t.Fatalf("add() = %v and %v, want the same fileID", a, b)
+	}
+	s := span{a, 0, 1}
+	if got := first.fileName(s) + " " + first.position(s).String(); got != "a.yz line: 1 col: 1" {
+		t.Errorf("first = %q, want %q", got, "a.yz line: 1 col: 1")
+	}
+	s = span{b, 2, 3}
+	if got := second.fileName(s) + " " + second.position(s).String(); got != "b.yz line: 3 col: 1" {
+		t.Errorf("second = %q, want %q", got, "b.yz line: 3 col: 1")
+	}
+}
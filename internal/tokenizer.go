@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -27,49 +26,49 @@ const (
 	HASH       // #
 	THEN_ARROW // / =>
 	WHEN       // when
+	MATCH      // match
 
 	// literals
-	INTEGER // int
-	DECIMAL // dec
-	STRING  // str
+	INTEGER       // int
+	DECIMAL       // dec
+	STRING        // str
+	STRING_HEAD   // str(
+	STRING_MIDDLE // )str(
+	STRING_TAIL   // )str
+	BOOLEAN       // bool
 
 	// identifiers
-	IDENTIFIER          // id
-	TYPE_IDENTIFIER     // tid
-	NON_WORD_IDENTIFIER // nwid
-	BREAK               // BREAK
-	CONTINUE            // CONTINUE
-	RETURN              // RETURN
-	Unexpected          // Unexpected
+	IDENTIFIER              // id
+	TYPE_IDENTIFIER         // tid
+	GENERIC_TYPE_IDENTIFIER // gid
+	NON_WORD_IDENTIFIER     // nwid
+	BREAK                   // BREAK
+	CONTINUE                // CONTINUE
+	RETURN                  // RETURN
+	Unexpected              // Unexpected
 )
 
 type tokenType uint
 
 type Token struct {
-	pos  position
+	span span
 	tt   tokenType
 	data string
 }
 
-type position struct {
-	line int
-	col  int
-}
-
 type tokenizer struct {
-	filname   string
-	content   string
-	tokens    []Token
-	col       int
-	line      int
-	pos       int
-	keepGoing bool
+	file        fileID
+	content     string
+	tokens      []Token
+	pos         int
+	keepGoing   bool
+	diagnostics Diagnostics
 }
 
 func (tt tokenType) String() string {
-	descriptions := [27]string{
-		`EOF`, `(`, `)`, `{`, `}`, `[`, `]`, `,`, `:`, `;`, `.`, `=`, `==`, `#`, `=>`, `when`,
-		`int`, `dec`, `str`, `id`, `tid`, `nwid`, "BREAK", "CONTINUE", "RETURN", "Unexpected",
+	descriptions := [32]string{
+		`EOF`, `(`, `)`, `{`, `}`, `[`, `]`, `,`, `:`, `;`, `.`, `=`, `==`, `#`, `=>`, `when`, `match`,
+		`int`, `dec`, `str`, `str(`, `)str(`, `)str`, `bool`, `id`, `tid`, `gid`, `nwid`, "BREAK", "CONTINUE", "RETURN", "Unexpected",
 	}
 	vot := int(tt)
 	if vot > len(descriptions) {
@@ -79,58 +78,75 @@ func (tt tokenType) String() string {
 	}
 }
 
-func (p position) String() string {
-	return fmt.Sprintf("line: %d col: %d", p.line, p.col)
-}
-
-func pos(line, col int) position {
-	return position{line, col}
-}
-
 func (t Token) String() string {
 
 	switch t.tt {
-	case INTEGER, DECIMAL, STRING, IDENTIFIER, NON_WORD_IDENTIFIER, TYPE_IDENTIFIER:
+	case INTEGER, DECIMAL, STRING, STRING_HEAD, STRING_MIDDLE, STRING_TAIL, BOOLEAN, IDENTIFIER, NON_WORD_IDENTIFIER,
+		TYPE_IDENTIFIER, GENERIC_TYPE_IDENTIFIER:
 		return fmt.Sprintf("%s:%s ", t.tt, t.data)
 	default:
 		return fmt.Sprintf("%v ", t.tt)
 	}
 }
 
-// Tokenize converts the content into an array of tokens or returns an error if the content is not valid
-func Tokenize(path []string, content string) ([]Token, error) {
-	t := &tokenizer{path[len(path)-1], content, []Token{}, 0, 1, 0, true}
+// Tokenize converts the content into an array of tokens.
+// The file is registered in files under the path joined by "/", and every token has the span of
+// the file it was read from.
+// Invalid input is skipped, so the tokens are always returned, along with a *Diagnostics error
+// with every lexical error found, if any.
+func Tokenize(files *fileSet, path []string, content string) ([]Token, error) {
+	file := files.add(strings.Join(path, "/"), content)
+	t := &tokenizer{file, content, []Token{}, 0, true, Diagnostics{sources: files}}
 	tokens, e := t.tokenize()
 	return tokens, e
 }
 
+// addToken adds a token whose data is the source text that ends at the current position.
 func (t *tokenizer) addToken(tt tokenType, data string) {
-	col := t.col
-
+	start := t.pos
 	if tt != EOF {
-		dataLen := utf8.RuneCountInString(data)
-		col = t.col - dataLen + 1
+		start = t.pos - len(data)
 	}
-	t.tokens = append(t.tokens, Token{pos(t.line, col), tt, data})
+	t.tokens = append(t.tokens, Token{t.span(start, t.pos), tt, data})
 }
 
+func (t *tokenizer) span(start, end int) span {
+	return span{t.file, start, end}
+}
+
+// eof is the rune read at the end of the content, a utf8.RuneError is an invalid byte or a "\uFFFD".
+const eof rune = -1
+
 func (t *tokenizer) nextRune() rune {
-	r, w := utf8.DecodeRuneInString(t.content[t.pos:])
-	if r == utf8.RuneError {
+	if t.pos >= len(t.content) {
 		t.keepGoing = false
+		return eof
 	}
+	r, w := utf8.DecodeRuneInString(t.content[t.pos:])
 	t.pos += w
-	t.col++
 	return r
 }
 
 func (t *tokenizer) unReadRune(r rune) {
-	if r == utf8.RuneError {
-		t.keepGoing = false
-	} else {
-		t.pos -= utf8.RuneLen(r)
+	if r != eof {
+		_, w := utf8.DecodeLastRuneInString(t.content[:t.pos])
+		t.pos -= w
+	}
+}
+
+// isInvalidByte returns true if the rune r just read is a byte that is not valid UTF-8.
+func (t *tokenizer) isInvalidByte(r rune) bool {
+	if r != utf8.RuneError {
+		return false
 	}
-	t.col--
+	_, w := utf8.DecodeLastRuneInString(t.content[:t.pos])
+	return w == 1
+}
+
+// invalidByteError reports the invalid byte just read, it is skipped.
+func (t *tokenizer) invalidByteError() {
+	t.lexicalError(t.pos-1, t.pos, fmt.Sprintf("invalid UTF-8 byte %#x", t.content[t.pos-1])).
+		withHint("save the file with the UTF-8 encoding")
 }
 
 func (t *tokenizer) peek() rune {
@@ -143,44 +159,25 @@ func (t *tokenizer) skipComment() {
 	// read until new line or EOF
 	for {
 		r := t.nextRune()
-		if r == '\n' {
-			t.line++
-			t.col = 0
+		if r == '\n' || r == eof {
 			return
 		}
-		if r == utf8.RuneError {
-			t.keepGoing = false
-			return
-
-		}
 	}
 }
 
-func (t *tokenizer) skipMultilineComment() {
+// skipMultilineComment skips until the closing */, the opening /* starts at the start offset.
+func (t *tokenizer) skipMultilineComment(start int) {
 	r := t.nextRune()
-	if r == '\n' {
-		t.line++
-		t.col = 0
-	}
-	if r == utf8.RuneError {
-		t.addToken(Unexpected, fmt.Sprintf("[%s: line:%d: col:%d]: Syntax error: unterminated comment", t.filname, t.line, t.col))
-		t.keepGoing = false
-		return
-	}
 	for {
+		if r == eof {
+			t.lexicalError(start, t.pos, "unterminated comment")
+			return
+		}
 		if r == '*' && t.peek() == '/' {
 			t.nextRune()
-			if r == '\n' {
-				t.line++
-				t.col = 0
-			}
 			return
 		}
 		r = t.nextRune()
-		if r == '\n' {
-			t.line++
-			t.col = 0
-		}
 	}
 }
 
@@ -190,6 +187,10 @@ func lookupIdent(identifier string) tokenType {
 	for _, r := range runes {
 		allUpper = allUpper && unicode.IsUpper(r)
 	}
+	if allUpper && len(runes) == 1 {
+		// a type parameter of a generic type or block: T, the parser takes it as a variable outside of a type
+		return GENERIC_TYPE_IDENTIFIER
+	}
 	if allUpper {
 		return IDENTIFIER
 	}
@@ -205,12 +206,16 @@ func lookupIdent(identifier string) tokenType {
 		return THEN_ARROW
 	case "when":
 		return WHEN
+	case "match":
+		return MATCH
 	case "break":
 		return BREAK
 	case "continue":
 		return CONTINUE
 	case "return":
 		return RETURN
+	case "true", "false":
+		return BOOLEAN
 	default:
 		allNonLetter := true
 		for _, r := range runes {
@@ -223,19 +228,29 @@ func lookupIdent(identifier string) tokenType {
 	return IDENTIFIER
 }
 
+// addStringLiteral adds a STRING token. An unterminated string literal is reported
+// and added with the content read until the end of the file.
+// A string with placeholders, like "Hello, `name`!", is split in the literal text before, between
+// and after them, with the tokens of the expression of each placeholder in between:
+// STRING_HEAD "Hello, ", IDENTIFIER name, STRING_TAIL "!". A "`" in the text is written as "\`".
 func (t *tokenizer) addStringLiteral() {
+	start := t.pos
+	part := start // start of the literal text being read
 	opening := t.nextRune()
-	pos := position{t.line, t.col}
 	r := t.nextRune()
 	builder := strings.Builder{}
+	interpolated := false
 	for r != opening {
-		if r == utf8.RuneError {
-			t.addToken(Unexpected, fmt.Sprintf("[%s: line:%d: col:%d]: Syntax error: unterminated string literal", t.filname, t.line, t.col))
-			t.keepGoing = false
-			return
+		if r == eof {
+			t.lexicalError(start, t.pos, "unterminated string literal").
+				withHint("close the string with " + string(opening))
+			break
 		}
-		if r == '\\' {
+		if t.isInvalidByte(r) {
+			t.invalidByteError()
+		} else if r == '\\' {
 			// Handle escape sequences
+			escapeStart := t.pos - 1
 			next := t.nextRune()
 			switch next {
 			case 'n':
@@ -248,20 +263,89 @@ func (t *tokenizer) addStringLiteral() {
 				builder.WriteRune('"')
 			case '\'':
 				builder.WriteRune('\'')
+			case '`':
+				builder.WriteRune('`')
 			default:
+				if next == eof {
+					t.lexicalError(start, t.pos, "unterminated string literal")
+					t.tokens = append(t.tokens, Token{t.span(part, t.pos), STRING, builder.String()})
+					return
+				}
+				t.lexicalError(escapeStart, t.pos, "unknown escape sequence \\"+string(next))
 				builder.WriteRune('\\')
 				builder.WriteRune(next)
 			}
+		} else if r == '`' {
+			tt := STRING_HEAD
+			if interpolated {
+				tt = STRING_MIDDLE
+			}
+			t.tokens = append(t.tokens, Token{t.span(part, t.pos), tt, builder.String()})
+			interpolated = true
+			t.placeholder(t.pos-1, opening)
+			builder.Reset()
+			part = t.pos
+			if !t.keepGoing {
+				return
+			}
 		} else {
 			builder.WriteRune(r)
 		}
-		if r == '\n' {
-			t.line++
-			t.col = 0
-		}
 		r = t.nextRune()
 	}
-	t.tokens = append(t.tokens, Token{pos, STRING, builder.String()})
+	tt := STRING
+	if interpolated {
+		tt = STRING_TAIL
+	}
+	t.tokens = append(t.tokens, Token{t.span(part, t.pos), tt, builder.String()})
+}
+
+// addRawStringLiteral adds the STRING token of a string between backticks. It is written as is: it
+// can span several lines, "\" doesn't start an escape sequence and "`" doesn't start a placeholder.
+// Like in Go, the carriage returns are discarded. A raw string can't be written in a placeholder,
+// where "`" closes it. An unterminated raw string is reported and added with the content read until
+// the end of the file.
+func (t *tokenizer) addRawStringLiteral() {
+	start := t.pos
+	t.nextRune() // the opening `
+	builder := strings.Builder{}
+	for r := t.nextRune(); r != '`'; r = t.nextRune() {
+		if r == eof {
+			t.lexicalError(start, t.pos, "unterminated string literal").
+				withHint("close the string with `")
+			break
+		}
+		if t.isInvalidByte(r) {
+			t.invalidByteError()
+		} else if r != '\r' {
+			builder.WriteRune(r)
+		}
+	}
+	t.tokens = append(t.tokens, Token{t.span(start, t.pos), STRING, builder.String()})
+}
+
+// placeholder adds the tokens of the expression of a placeholder of a string, until the "`" that
+// closes it. The start is the offset of the "`" that opens it. The placeholder is in a single line
+// and the strings in it use the other quote: "Hi `name + '!'`".
+func (t *tokenizer) placeholder(start int, quote rune) {
+	tokens := len(t.tokens)
+	for r := t.nextRune(); r != '`'; r = t.nextRune() {
+		if r == '\n' || r == quote || r == eof {
+			// the rest of the line is part of the string
+			t.unReadRune(r)
+			t.lexicalError(start, t.pos, "unterminated placeholder").
+				withHint("close the placeholder with ` in the same line")
+			return
+		}
+		t.token(r)
+		if !t.keepGoing {
+			return
+		}
+	}
+	if len(t.tokens) == tokens {
+		t.lexicalError(start, t.pos, "empty placeholder").
+			withHint("write an expression between the backticks, like `name`, or escape the backtick with \\`")
+	}
 }
 
 func (t *tokenizer) isIdentifier(r rune) bool {
@@ -271,7 +355,7 @@ func (t *tokenizer) isIdentifier(r rune) bool {
 func (t *tokenizer) readIdentifier() string {
 	builder := strings.Builder{}
 	r := t.nextRune()
-	for (unicode.IsPrint(r) || unicode.IsDigit(r)) && !strings.ContainsRune("{}[]#().,:;\"'`", r) && !unicode.IsSpace(r) && r != utf8.RuneError {
+	for (unicode.IsPrint(r) || unicode.IsDigit(r)) && !strings.ContainsRune("{}[]#().,:;\"'`", r) && !unicode.IsSpace(r) && !t.isInvalidByte(r) {
 		builder.WriteRune(r)
 		r = t.nextRune()
 	}
@@ -317,76 +401,90 @@ func (t *tokenizer) tokenize() ([]Token, error) {
 	for r := t.nextRune(); t.keepGoing; r = t.nextRune() {
 		if r == '\n' {
 			t.addCommaIfNeeded()
-			t.line++
-			t.col = 0
 		}
-		if unicode.IsSpace(r) {
-			continue
-		}
-		switch r {
-		case '{':
-			t.addToken(LBRACE, "{")
-		case '}':
-			t.addToken(RBRACE, "}")
-		case ':':
-			t.addToken(COLON, ":")
-		case ';':
-			t.addToken(SEMICOLON, ";")
-		case '.':
-			t.addToken(PERIOD, ".")
-		case '(':
-			t.addToken(LPAREN, "(")
-		case ')':
-			t.addToken(RPAREN, ")")
-		case '[':
-			t.addToken(LBRACKET, "[")
-		case ']':
-			t.addToken(RBRACKET, "]")
-		case ',':
-			t.addToken(COMMA, ",")
-		case '#':
-			t.addToken(HASH, "#")
-		case '"', '\'':
+		t.token(r)
+	}
+
+	t.addToken(EOF, "EOF")
+	if len(t.diagnostics.list) > 0 {
+		return t.tokens, &t.diagnostics
+	}
+	return t.tokens, nil
+}
+
+// token adds the token that starts with the rune r, the spaces are skipped.
+func (t *tokenizer) token(r rune) {
+	if unicode.IsSpace(r) {
+		return
+	}
+	switch r {
+	case '{':
+		t.addToken(LBRACE, "{")
+	case '}':
+		t.addToken(RBRACE, "}")
+	case ':':
+		t.addToken(COLON, ":")
+	case ';':
+		t.addToken(SEMICOLON, ";")
+	case '.':
+		t.addToken(PERIOD, ".")
+	case '(':
+		t.addToken(LPAREN, "(")
+	case ')':
+		t.addToken(RPAREN, ")")
+	case '[':
+		t.addToken(LBRACKET, "[")
+	case ']':
+		t.addToken(RBRACKET, "]")
+	case ',':
+		t.addToken(COMMA, ",")
+	case '#':
+		t.addToken(HASH, "#")
+	case '"', '\'':
+		t.unReadRune(r)
+		t.addStringLiteral()
+	case '`':
+		t.unReadRune(r)
+		t.addRawStringLiteral()
+	default:
+		switch {
+		case t.isInvalidByte(r):
+			t.invalidByteError()
+		case r == '-' && unicode.IsDigit(t.peek()):
 			t.unReadRune(r)
-			t.addStringLiteral()
-		default:
-			switch {
-			case r == '-' && unicode.IsDigit(t.peek()):
-				t.unReadRune(r)
-				t.addNegativeNumber()
-				break
-			case unicode.IsDigit(r):
-				t.unReadRune(r)
-				t.addNumber()
-			case r == '/':
-				if t.peek() == '/' {
-					t.nextRune()
-					t.skipComment()
-					continue
-				} else if t.peek() == '*' {
-					t.nextRune()
-					t.skipMultilineComment()
-					continue
-				}
-				fallthrough // add `/` as an identifier Token
-			case t.isIdentifier(r):
-				t.unReadRune(r)
-				id := t.readIdentifier()
-				t.addToken(lookupIdent(id), id)
-			default:
-				unexpectedTokenMessage := fmt.Sprintf("[%s: line:%d: col:%d]: Unexpected Token %s", t.filname, t.line, t.col, string(r))
-				t.addToken(Unexpected, unexpectedTokenMessage)
-				return t.tokens, errors.New(unexpectedTokenMessage)
+			t.addNegativeNumber()
+			break
+		case unicode.IsDigit(r):
+			t.unReadRune(r)
+			t.addNumber()
+		case r == '/':
+			if t.peek() == '/' {
+				t.nextRune()
+				t.skipComment()
+				return
+			} else if t.peek() == '*' {
+				start := t.pos - 1
+				t.nextRune()
+				t.skipMultilineComment(start)
+				return
 			}
+			fallthrough // add `/` as an identifier Token
+		case t.isIdentifier(r):
+			t.unReadRune(r)
+			id := t.readIdentifier()
+			t.addToken(lookupIdent(id), id)
+		default:
+			// skip it and keep going
+			t.lexicalError(t.pos-utf8.RuneLen(r), t.pos, "unexpected character "+string(r))
 		}
 	}
+}
 
-	if len(t.tokens) > 0 && t.tokens[len(t.tokens)-1].tt == Unexpected {
-		return t.tokens, errors.New(t.tokens[len(t.tokens)-1].data)
-	} else {
-		t.addToken(EOF, "EOF")
-		return t.tokens, nil
-	}
+// lexicalError records an error for the source text between the start and end offsets and returns it.
+func (t *tokenizer) lexicalError(start, end int, message string) *Diagnostic {
+	d := newDiagnostic(t.span(start, end), codeLexical, message)
+	t.diagnostics.Add(d)
+	return d
 }
 
 func (t *tokenizer) addCommaIfNeeded() {
@@ -395,13 +493,19 @@ func (t *tokenizer) addCommaIfNeeded() {
 		if last.tt == IDENTIFIER ||
 			last.tt == NON_WORD_IDENTIFIER ||
 			last.tt == TYPE_IDENTIFIER ||
+			last.tt == GENERIC_TYPE_IDENTIFIER ||
 			last.tt == INTEGER ||
 			last.tt == DECIMAL ||
 			last.tt == STRING ||
+			last.tt == STRING_TAIL ||
+			last.tt == BOOLEAN ||
 			last.tt == RBRACE ||
 			last.tt == RPAREN ||
-			last.tt == RBRACKET {
-			t.addToken(COMMA, ",")
+			last.tt == RBRACKET ||
+			last.tt == RETURN ||
+			last.tt == BREAK ||
+			last.tt == CONTINUE {
+			t.addToken(COMMA, "\n")
 		}
 	}
 }
@@ -5,12 +5,29 @@ import (
 	"testing"
 )
 
+// tokenAt is a token with the line and column where it starts instead of its span,
+// to write the expected tokens.
+type tokenAt struct {
+	pos  position
+	tt   tokenType
+	data string
+}
+
+func at(files *fileSet, t Token) tokenAt {
+	return tokenAt{files.position(t.span), t.tt, t.data}
+}
+
+// sp is the span [start, end) of the first file tokenized in a fileSet.
+func sp(start, end int) span {
+	return span{1, start, end}
+}
+
 func TestTokenizer_Tokenize(t *testing.T) {
 	tests := []struct {
 		name    string
 		path    []string
 		content string
-		want    []Token
+		want    []tokenAt
 	}{
 
 		{
@@ -18,7 +35,7 @@ func TestTokenizer_Tokenize(t *testing.T) {
 			[]string{"test.yz"},
 			`( ) { } [ ] , : ; . = # => when
 1 1.0 "Hello, World!" 'Hello, name!' a Point + break continue return /* This is a block comment */ // This is a line comment`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: LPAREN, data: "("},
 				{pos: position{line: 1, col: 3}, tt: RPAREN, data: ")"},
 				{pos: position{line: 1, col: 5}, tt: LBRACE, data: "{"},
@@ -43,15 +60,39 @@ func TestTokenizer_Tokenize(t *testing.T) {
 				{pos: position{line: 2, col: 48}, tt: BREAK, data: "break"},
 				{pos: position{line: 2, col: 54}, tt: CONTINUE, data: "continue"},
 				{pos: position{line: 2, col: 63}, tt: RETURN, data: "return"},
-				{pos: position{line: 2, col: 126}, tt: EOF, data: "EOF"},
+				{pos: position{line: 2, col: 125}, tt: EOF, data: "EOF"},
+			},
+		},
+		{
+			"Boolean literals",
+			[]string{"test.yz"},
+			"ready: true\nfalse",
+			[]tokenAt{
+				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "ready"},
+				{pos: position{line: 1, col: 6}, tt: COLON, data: ":"},
+				{pos: position{line: 1, col: 8}, tt: BOOLEAN, data: "true"},
+				{pos: position{line: 1, col: 12}, tt: COMMA, data: "\n"},
+				{pos: position{line: 2, col: 1}, tt: BOOLEAN, data: "false"},
+				{pos: position{line: 2, col: 6}, tt: EOF, data: "EOF"},
 			},
 		},
 		{
 			"Line comment + EOF",
 			[]string{"test.yz"},
 			`// This is a comment`,
-			[]Token{
-				{pos: position{line: 1, col: 22}, tt: EOF, data: "EOF"},
+			[]tokenAt{
+				{pos: position{line: 1, col: 21}, tt: EOF, data: "EOF"},
+			},
+		},
+		{
+			"Line break after return",
+			[]string{"test.yz"},
+			"return\na",
+			[]tokenAt{
+				{pos: position{line: 1, col: 1}, tt: RETURN, data: "return"},
+				{pos: position{line: 1, col: 7}, tt: COMMA, data: "\n"},
+				{pos: position{line: 2, col: 1}, tt: IDENTIFIER, data: "a"},
+				{pos: position{line: 2, col: 2}, tt: EOF, data: "EOF"},
 			},
 		},
 		{
@@ -59,7 +100,7 @@ func TestTokenizer_Tokenize(t *testing.T) {
 			[]string{"test.yz"},
 			`// This is a comment
 1 + 2`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 2, col: 1}, tt: INTEGER, data: "1"},
 				{pos: position{line: 2, col: 3}, tt: NON_WORD_IDENTIFIER, data: "+"},
 				{pos: position{line: 2, col: 5}, tt: INTEGER, data: "2"},
@@ -71,7 +112,7 @@ func TestTokenizer_Tokenize(t *testing.T) {
 			[]string{"test.yz"},
 			`1 + 2 // This is a comment
 a: 3`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: INTEGER, data: "1"},
 				{pos: position{line: 1, col: 3}, tt: NON_WORD_IDENTIFIER, data: "+"},
 				{pos: position{line: 1, col: 5}, tt: INTEGER, data: "2"},
@@ -88,11 +129,11 @@ a: 3`,
 that spans multiple lines
 */
 b:2`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "a"},
 				{pos: position{line: 1, col: 2}, tt: COLON, data: ":"},
 				{pos: position{line: 1, col: 3}, tt: INTEGER, data: "1"},
-				{pos: position{line: 3, col: 3}, tt: COMMA, data: ","},
+				{pos: position{line: 3, col: 3}, tt: COMMA, data: "\n"},
 				{pos: position{line: 4, col: 1}, tt: IDENTIFIER, data: "b"},
 				{pos: position{line: 4, col: 2}, tt: COLON, data: ":"},
 				{pos: position{line: 4, col: 3}, tt: INTEGER, data: "2"},
@@ -103,18 +144,18 @@ b:2`,
 			"Block comment followed by line comment",
 			[]string{"test.yz"},
 			`a:1 /* This is a block comment */ // This is a line comment`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "a"},
 				{pos: position{line: 1, col: 2}, tt: COLON, data: ":"},
 				{pos: position{line: 1, col: 3}, tt: INTEGER, data: "1"},
-				{pos: position{line: 1, col: 61}, tt: EOF, data: "EOF"},
+				{pos: position{line: 1, col: 60}, tt: EOF, data: "EOF"},
 			},
 		},
 		{
 			"String literals",
 			[]string{"test.yz"},
 			`"Hello, World!"`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: STRING, data: "Hello, World!"},
 				{pos: position{line: 1, col: 16}, tt: EOF, data: "EOF"},
 			},
@@ -124,7 +165,7 @@ b:2`,
 			[]string{"test.yz"},
 			`"Hi,
 World!"`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: STRING, data: "Hi,\nWorld!"},
 				{pos: position{line: 2, col: 8}, tt: EOF, data: "EOF"},
 			},
@@ -134,7 +175,7 @@ World!"`,
 			[]string{"test.yz"},
 			`"Hi, \"
 World!"`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: STRING, data: "Hi, \"\nWorld!"},
 				{pos: position{line: 2, col: 8}, tt: EOF, data: "EOF"},
 			},
@@ -146,7 +187,7 @@ World!"`,
 			`"Hi,   
 	World!   .
 	."`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: STRING, data: "Hi,   \n\tWorld!   .\n\t."},
 				{pos: position{line: 3, col: 4}, tt: EOF, data: "EOF"},
 			},
@@ -155,7 +196,7 @@ World!"`,
 			"String literals with various escapes",
 			[]string{"test.yz"},
 			`"The quick\nbrown fox\tjumps over\\the lazy dog\"She said, 'Hello'\\xUnknown escape"`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: STRING, data: "The quick\nbrown fox\tjumps over\\the lazy dog\"She said, 'Hello'\\xUnknown escape"},
 				{pos: position{line: 1, col: 85}, tt: EOF, data: "EOF"},
 			},
@@ -163,17 +204,47 @@ World!"`,
 		{
 			"String interpolation",
 			[]string{"test.yz"},
-			"'Hello, `name`!'",
-			[]Token{
-				{pos: position{line: 1, col: 1}, tt: STRING, data: "Hello, `name`!"},
-				{pos: position{line: 1, col: 17}, tt: EOF, data: "EOF"},
+			"'Hello, `name`!' \"`a` + `b.c` = \\`\"",
+			[]tokenAt{
+				{pos: position{line: 1, col: 1}, tt: STRING_HEAD, data: "Hello, "},
+				{pos: position{line: 1, col: 10}, tt: IDENTIFIER, data: "name"},
+				{pos: position{line: 1, col: 15}, tt: STRING_TAIL, data: "!"},
+				{pos: position{line: 1, col: 18}, tt: STRING_HEAD, data: ""},
+				{pos: position{line: 1, col: 20}, tt: IDENTIFIER, data: "a"},
+				{pos: position{line: 1, col: 22}, tt: STRING_MIDDLE, data: " + "},
+				{pos: position{line: 1, col: 26}, tt: IDENTIFIER, data: "b"},
+				{pos: position{line: 1, col: 27}, tt: PERIOD, data: "."},
+				{pos: position{line: 1, col: 28}, tt: IDENTIFIER, data: "c"},
+				{pos: position{line: 1, col: 30}, tt: STRING_TAIL, data: " = `"},
+				{pos: position{line: 1, col: 36}, tt: EOF, data: "EOF"},
+			},
+		},
+		{
+			"Raw string literals",
+			[]string{"test.yz"},
+			"a: `C:\\dir \"`name`\"`\nb: `one\r\n  two`\nc: ``",
+			[]tokenAt{
+				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "a"},
+				{pos: position{line: 1, col: 2}, tt: COLON, data: ":"},
+				{pos: position{line: 1, col: 4}, tt: STRING, data: "C:\\dir \""},
+				{pos: position{line: 1, col: 14}, tt: IDENTIFIER, data: "name"},
+				{pos: position{line: 1, col: 18}, tt: STRING, data: "\""},
+				{pos: position{line: 1, col: 21}, tt: COMMA, data: "\n"},
+				{pos: position{line: 2, col: 1}, tt: IDENTIFIER, data: "b"},
+				{pos: position{line: 2, col: 2}, tt: COLON, data: ":"},
+				{pos: position{line: 2, col: 4}, tt: STRING, data: "one\n  two"},
+				{pos: position{line: 3, col: 7}, tt: COMMA, data: "\n"},
+				{pos: position{line: 4, col: 1}, tt: IDENTIFIER, data: "c"},
+				{pos: position{line: 4, col: 2}, tt: COLON, data: ":"},
+				{pos: position{line: 4, col: 4}, tt: STRING, data: ""},
+				{pos: position{line: 4, col: 6}, tt: EOF, data: "EOF"},
 			},
 		},
 		{
 			"String literals",
 			[]string{"test.yz"},
 			`["One" 'Two' "'Three'" '"Four"']`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: LBRACKET, data: "["},
 				{pos: position{line: 1, col: 2}, tt: STRING, data: "One"},
 				{pos: position{line: 1, col: 8}, tt: STRING, data: "Two"},
@@ -187,7 +258,7 @@ World!"`,
 			"Integers",
 			[]string{"test.yz"},
 			`1 9876324`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: INTEGER, data: "1"},
 				{pos: position{line: 1, col: 3}, tt: INTEGER, data: "9876324"},
 				{pos: position{line: 1, col: 10}, tt: EOF, data: "EOF"},
@@ -197,7 +268,7 @@ World!"`,
 			"Decimal literals",
 			[]string{"test.yz"},
 			`1.0`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: DECIMAL, data: "1.0"},
 				{pos: position{line: 1, col: 4}, tt: EOF, data: "EOF"},
 			},
@@ -208,19 +279,19 @@ World!"`,
 			`minusThree: -1 - -2.0
 plusOne: -1 + -2.0
 `,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "minusThree"},
 				{pos: position{line: 1, col: 11}, tt: COLON, data: ":"},
 				{pos: position{line: 1, col: 13}, tt: INTEGER, data: "-1"},
 				{pos: position{line: 1, col: 16}, tt: NON_WORD_IDENTIFIER, data: "-"},
 				{pos: position{line: 1, col: 18}, tt: DECIMAL, data: "-2.0"},
-				{pos: position{line: 1, col: 22}, tt: COMMA, data: ","},
+				{pos: position{line: 1, col: 22}, tt: COMMA, data: "\n"},
 				{pos: position{line: 2, col: 1}, tt: IDENTIFIER, data: "plusOne"},
 				{pos: position{line: 2, col: 8}, tt: COLON, data: ":"},
 				{pos: position{line: 2, col: 10}, tt: INTEGER, data: "-1"},
 				{pos: position{line: 2, col: 13}, tt: NON_WORD_IDENTIFIER, data: "+"},
 				{pos: position{line: 2, col: 15}, tt: DECIMAL, data: "-2.0"},
-				{pos: position{line: 2, col: 19}, tt: COMMA, data: ","},
+				{pos: position{line: 2, col: 19}, tt: COMMA, data: "\n"},
 				{pos: position{line: 3, col: 1}, tt: EOF, data: "EOF"},
 			},
 		},
@@ -229,7 +300,7 @@ plusOne: -1 + -2.0
 			"Equals sign examples",
 			[]string{"test.yz"},
 			`== => =< =a =`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: EQUALS, data: "=="},
 				{pos: position{line: 1, col: 4}, tt: THEN_ARROW, data: "=>"},
 				{pos: position{line: 1, col: 7}, tt: NON_WORD_IDENTIFIER, data: "=<"},
@@ -242,7 +313,7 @@ plusOne: -1 + -2.0
 			"Non ascii characters",
 			[]string{"test.yz"},
 			`message: 👋🌍`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "message"},
 				{pos: position{line: 1, col: 8}, tt: COLON, data: ":"},
 				{pos: position{line: 1, col: 10}, tt: NON_WORD_IDENTIFIER, data: "👋🌍"},
@@ -253,7 +324,7 @@ plusOne: -1 + -2.0
 			"Non word identifiers examples",
 			[]string{"test.yz"},
 			`+ - * / % ~ < > ! & | ^ += -= /= ~= != <= >= && || ++ -- >>= <<= >> << |> <- -> `,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: NON_WORD_IDENTIFIER, data: "+"},
 				{pos: position{line: 1, col: 3}, tt: NON_WORD_IDENTIFIER, data: "-"},
 				{pos: position{line: 1, col: 5}, tt: NON_WORD_IDENTIFIER, data: "*"},
@@ -292,7 +363,7 @@ plusOne: -1 + -2.0
 			"Printable UTF-8 characters (additional symbols and emojis)",
 			[]string{"test.yz"},
 			`©®™✓✔✕✖✗✘✙✚✛✜✢✣✤✥✦✧✨⭐✩✪✫✬✭✮✯✰✱✲✳✴✵✶✷✸✹✺✻✼✽✾✿❀❁❂❃❄❅❆❇❈❉❊❋❌❍❎❏❐❑❒❖❗❘❙❚❛❜❝❞❡❢❣❤❥❦❧`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: NON_WORD_IDENTIFIER, data: "©®™✓✔✕✖✗✘✙✚✛✜✢✣✤✥✦✧✨⭐✩✪✫✬✭✮✯✰✱✲✳✴✵✶✷✸✹✺✻✼✽✾✿❀❁❂❃❄❅❆❇❈❉❊❋❌❍❎❏❐❑❒❖❗❘❙❚❛❜❝❞❡❢❣❤❥❦❧"},
 				{pos: position{line: 1, col: 80}, tt: EOF, data: "EOF"},
 			},
@@ -301,7 +372,7 @@ plusOne: -1 + -2.0
 			name:    "Common mathematical symbols in Unicode and UTF-8",
 			path:    []string{"test.yz"},
 			content: `∑ ∏ ∫ ∞ √ ∇ ≈ ≠ ≤ ≥ ± ∂ ∃ ∀ ∈ ∉ ∋ ∅ ∧ ∨ ∩ ∪ ⊂ ⊃ ⊆ ⊇ ⊕ ⊗ ⊥`,
-			want: []Token{
+			want: []tokenAt{
 				{pos: position{line: 1, col: 1}, tt: NON_WORD_IDENTIFIER, data: "∑"},
 				{pos: position{line: 1, col: 3}, tt: NON_WORD_IDENTIFIER, data: "∏"},
 				{pos: position{line: 1, col: 5}, tt: NON_WORD_IDENTIFIER, data: "∫"},
@@ -349,51 +420,51 @@ plusOne: -1 + -2.0
 γειά: "greek"
 æøå: "nordic"
 `,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "¡hola!"},
 				{pos: position{line: 1, col: 7}, tt: COLON, data: ":"},
 				{pos: position{line: 1, col: 9}, tt: STRING, data: "latin"},
-				{pos: position{line: 1, col: 16}, tt: COMMA, data: ","},
+				{pos: position{line: 1, col: 16}, tt: COMMA, data: "\n"},
 				{pos: position{line: 2, col: 1}, tt: IDENTIFIER, data: "привет"},
 				{pos: position{line: 2, col: 7}, tt: COLON, data: ":"},
 				{pos: position{line: 2, col: 9}, tt: STRING, data: "cyrillic"},
-				{pos: position{line: 2, col: 19}, tt: COMMA, data: ","},
+				{pos: position{line: 2, col: 19}, tt: COMMA, data: "\n"},
 				{pos: position{line: 3, col: 1}, tt: IDENTIFIER, data: "变量"},
 				{pos: position{line: 3, col: 3}, tt: COLON, data: ":"},
 				{pos: position{line: 3, col: 5}, tt: STRING, data: "chinese"},
-				{pos: position{line: 3, col: 14}, tt: COMMA, data: ","},
+				{pos: position{line: 3, col: 14}, tt: COMMA, data: "\n"},
 				{pos: position{line: 4, col: 1}, tt: IDENTIFIER, data: "변수"},
 				{pos: position{line: 4, col: 3}, tt: COLON, data: ":"},
 				{pos: position{line: 4, col: 5}, tt: STRING, data: "korean"},
-				{pos: position{line: 4, col: 13}, tt: COMMA, data: ","},
+				{pos: position{line: 4, col: 13}, tt: COMMA, data: "\n"},
 				{pos: position{line: 5, col: 1}, tt: IDENTIFIER, data: "変数"},
 				{pos: position{line: 5, col: 3}, tt: COLON, data: ":"},
 				{pos: position{line: 5, col: 5}, tt: STRING, data: "japanese"},
-				{pos: position{line: 5, col: 15}, tt: COMMA, data: ","},
+				{pos: position{line: 5, col: 15}, tt: COMMA, data: "\n"},
 				{pos: position{line: 6, col: 1}, tt: IDENTIFIER, data: "über"},
 				{pos: position{line: 6, col: 5}, tt: COLON, data: ":"},
 				{pos: position{line: 6, col: 7}, tt: STRING, data: "german"},
-				{pos: position{line: 6, col: 15}, tt: COMMA, data: ","},
+				{pos: position{line: 6, col: 15}, tt: COMMA, data: "\n"},
 				{pos: position{line: 7, col: 1}, tt: IDENTIFIER, data: "नमस्ते"},
 				{pos: position{line: 7, col: 7}, tt: COLON, data: ":"},
 				{pos: position{line: 7, col: 9}, tt: STRING, data: "hindi"},
-				{pos: position{line: 7, col: 16}, tt: COMMA, data: ","},
+				{pos: position{line: 7, col: 16}, tt: COMMA, data: "\n"},
 				{pos: position{line: 8, col: 1}, tt: IDENTIFIER, data: "สวัสดี"},
 				{pos: position{line: 8, col: 7}, tt: COLON, data: ":"},
 				{pos: position{line: 8, col: 9}, tt: STRING, data: "thai"},
-				{pos: position{line: 8, col: 15}, tt: COMMA, data: ","},
+				{pos: position{line: 8, col: 15}, tt: COMMA, data: "\n"},
 				{pos: position{line: 9, col: 1}, tt: IDENTIFIER, data: "ሰላም"},
 				{pos: position{line: 9, col: 4}, tt: COLON, data: ":"},
 				{pos: position{line: 9, col: 6}, tt: STRING, data: "amharic"},
-				{pos: position{line: 9, col: 15}, tt: COMMA, data: ","},
+				{pos: position{line: 9, col: 15}, tt: COMMA, data: "\n"},
 				{pos: position{line: 10, col: 1}, tt: IDENTIFIER, data: "γειά"},
 				{pos: position{line: 10, col: 5}, tt: COLON, data: ":"},
 				{pos: position{line: 10, col: 7}, tt: STRING, data: "greek"},
-				{pos: position{line: 10, col: 14}, tt: COMMA, data: ","},
+				{pos: position{line: 10, col: 14}, tt: COMMA, data: "\n"},
 				{pos: position{line: 11, col: 1}, tt: IDENTIFIER, data: "æøå"},
 				{pos: position{line: 11, col: 4}, tt: COLON, data: ":"},
 				{pos: position{line: 11, col: 6}, tt: STRING, data: "nordic"},
-				{pos: position{line: 11, col: 14}, tt: COMMA, data: ","},
+				{pos: position{line: 11, col: 14}, tt: COMMA, data: "\n"},
 				{pos: position{line: 12, col: 1}, tt: EOF, data: "EOF"},
 			},
 		},
@@ -402,7 +473,7 @@ plusOne: -1 + -2.0
 			"Simple",
 			[]string{"test.yz"},
 			`1 + 2`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: INTEGER, data: "1"},
 				{pos: position{line: 1, col: 3}, tt: NON_WORD_IDENTIFIER, data: "+"},
 				{pos: position{line: 1, col: 5}, tt: INTEGER, data: "2"},
@@ -413,7 +484,7 @@ plusOne: -1 + -2.0
 			"Simple with spaces",
 			[]string{"test.yz"},
 			`1 + 2`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: INTEGER, data: "1"},
 				{pos: position{line: 1, col: 3}, tt: NON_WORD_IDENTIFIER, data: "+"},
 				{pos: position{line: 1, col: 5}, tt: INTEGER, data: "2"},
@@ -425,11 +496,11 @@ plusOne: -1 + -2.0
 			[]string{"test.yz"},
 			`1 + 2
 `,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: INTEGER, data: "1"},
 				{pos: position{line: 1, col: 3}, tt: NON_WORD_IDENTIFIER, data: "+"},
 				{pos: position{line: 1, col: 5}, tt: INTEGER, data: "2"},
-				{pos: position{line: 1, col: 6}, tt: COMMA, data: ","},
+				{pos: position{line: 1, col: 6}, tt: COMMA, data: "\n"},
 				{pos: position{line: 2, col: 1}, tt: EOF, data: "EOF"},
 			},
 		},
@@ -438,11 +509,11 @@ plusOne: -1 + -2.0
 			[]string{"test.yz"},
 			`1 + 2
 `,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: INTEGER, data: "1"},
 				{pos: position{line: 1, col: 3}, tt: NON_WORD_IDENTIFIER, data: "+"},
 				{pos: position{line: 1, col: 5}, tt: INTEGER, data: "2"},
-				{pos: position{line: 1, col: 6}, tt: COMMA, data: ","},
+				{pos: position{line: 1, col: 6}, tt: COMMA, data: "\n"},
 				{pos: position{line: 2, col: 1}, tt: EOF, data: "EOF"},
 			},
 		},
@@ -450,7 +521,7 @@ plusOne: -1 + -2.0
 			"Hash and parenthesis",
 			[]string{"test.yz"},
 			`fn #(Int)`,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "fn"},
 				{pos: position{line: 1, col: 4}, tt: HASH, data: "#"},
 				{pos: position{line: 1, col: 5}, tt: LPAREN, data: "("},
@@ -459,21 +530,53 @@ plusOne: -1 + -2.0
 				{pos: position{line: 1, col: 10}, tt: EOF, data: "EOF"},
 			},
 		},
+		{
+			"Generic type identifiers",
+			[]string{"test.yz"},
+			`id #(T, x T, T)
+MAX`,
+			[]tokenAt{
+				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "id"},
+				{pos: position{line: 1, col: 4}, tt: HASH, data: "#"},
+				{pos: position{line: 1, col: 5}, tt: LPAREN, data: "("},
+				{pos: position{line: 1, col: 6}, tt: GENERIC_TYPE_IDENTIFIER, data: "T"},
+				{pos: position{line: 1, col: 7}, tt: COMMA, data: ","},
+				{pos: position{line: 1, col: 9}, tt: IDENTIFIER, data: "x"},
+				{pos: position{line: 1, col: 11}, tt: GENERIC_TYPE_IDENTIFIER, data: "T"},
+				{pos: position{line: 1, col: 12}, tt: COMMA, data: ","},
+				{pos: position{line: 1, col: 14}, tt: GENERIC_TYPE_IDENTIFIER, data: "T"},
+				{pos: position{line: 1, col: 15}, tt: RPAREN, data: ")"},
+				{pos: position{line: 1, col: 16}, tt: COMMA, data: "\n"},
+				{pos: position{line: 2, col: 1}, tt: IDENTIFIER, data: "MAX"},
+				{pos: position{line: 2, col: 4}, tt: EOF, data: "EOF"},
+			},
+		},
 
 		{
 			"Empty file",
 			[]string{"test.yz"},
 			``,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 1, col: 1}, tt: EOF, data: "EOF"},
 			},
 		},
+		{
+			"Replacement character in a string",
+			[]string{"test.yz"},
+			"s: \"a\uFFFDb\"",
+			[]tokenAt{
+				{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "s"},
+				{pos: position{line: 1, col: 2}, tt: COLON, data: ":"},
+				{pos: position{line: 1, col: 4}, tt: STRING, data: "a\uFFFDb"},
+				{pos: position{line: 1, col: 9}, tt: EOF, data: "EOF"},
+			},
+		},
 		{
 			"Empty file with newline",
 			[]string{"test.yz"},
 			`  
   `,
-			[]Token{
+			[]tokenAt{
 				{pos: position{line: 2, col: 3}, tt: EOF, data: "EOF"},
 			},
 		},
@@ -481,7 +584,8 @@ plusOne: -1 + -2.0
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, e := Tokenize(tt.path, tt.content)
+			files := &fileSet{}
+			got, e := Tokenize(files, tt.path, tt.content)
 			if e != nil {
 				t.Errorf("Tokenize() error = %v", e)
 				return
@@ -491,10 +595,10 @@ plusOne: -1 + -2.0
 				return
 			}
 			for i := range got {
-				if got[i] != tt.want[i] {
+				if at(files, got[i]) != tt.want[i] {
 					t.Errorf("Tokenize() = \ngot =  %v\n"+
 						"       (pos:%v), \nwant = %v\n"+
-						"       (pos:%v)", got, got[i].pos, tt.want, tt.want[i].pos)
+						"       (pos:%v)", got, files.position(got[i].span), tt.want, tt.want[i].pos)
 					return
 				}
 			}
@@ -513,24 +617,69 @@ func TestTokenizer_SyntaxErr(t *testing.T) {
 			"Unclosed string",
 			[]string{"test.yz"},
 			`"`,
-			fmt.Errorf("[test.yz: line:1: col:2]: Syntax error: unterminated string literal"),
+			fmt.Errorf("[test.yz: line: 1 col: 1] unterminated string literal\nHint: close the string with \""),
 		},
 		{
 			"Unclosed multiline comment",
 			[]string{"test.yz"},
 			`/*`,
-			fmt.Errorf("[test.yz: line:1: col:3]: Syntax error: unterminated comment"),
+			fmt.Errorf("[test.yz: line: 1 col: 1] unterminated comment"),
+		},
+		{
+			"Unterminated raw string",
+			[]string{"test.yz"},
+			"a: `never\nclosed",
+			fmt.Errorf("[test.yz: line: 1 col: 4] unterminated string literal\n" +
+				"Hint: close the string with `"),
+		},
+		{
+			"Unclosed multiline comment after code",
+			[]string{"test.yz"},
+			`a: 1
+b: 2 /* never
+closed`,
+			fmt.Errorf("[test.yz: line: 2 col: 6] unterminated comment"),
+		},
+		{
+			"Unknown escape sequence",
+			[]string{"test.yz"},
+			`"tab\tand \q"`,
+			fmt.Errorf("[test.yz: line: 1 col: 11] unknown escape sequence \\q"),
+		},
+		{
+			"Invalid placeholders",
+			[]string{"test.yz"},
+			"a: \"Hi `name!\"\nb: 'one `` two'",
+			fmt.Errorf("[test.yz: line: 1 col: 8] unterminated placeholder\n" +
+				"Hint: close the placeholder with ` in the same line\n" +
+				"[test.yz: line: 2 col: 9] empty placeholder\n" +
+				"Hint: write an expression between the backticks, like `name`, or escape the backtick with \\`"),
 		},
 		{
-			"Backtick strings",
+			"Invalid UTF-8 bytes",
 			[]string{"test.yz"},
-			"`hola`",
-			fmt.Errorf("[test.yz: line:1: col:1]: Unexpected Token `"),
+			"s: 1\n\xc3\nt: \"x\xffy\"\n\xfe",
+			fmt.Errorf("[test.yz: line: 2 col: 1] invalid UTF-8 byte 0xc3\n" +
+				"Hint: save the file with the UTF-8 encoding\n" +
+				"[test.yz: line: 3 col: 6] invalid UTF-8 byte 0xff\n" +
+				"Hint: save the file with the UTF-8 encoding\n" +
+				"[test.yz: line: 4 col: 1] invalid UTF-8 byte 0xfe\n" +
+				"Hint: save the file with the UTF-8 encoding"),
+		},
+		{
+			"Every error is reported",
+			[]string{"test.yz"},
+			"a: \"Hi `x\"\nb: \"\\w\"\nc: 'open",
+			fmt.Errorf("[test.yz: line: 1 col: 8] unterminated placeholder\n" +
+				"Hint: close the placeholder with ` in the same line\n" +
+				"[test.yz: line: 2 col: 5] unknown escape sequence \\w\n" +
+				"[test.yz: line: 3 col: 4] unterminated string literal\n" +
+				"Hint: close the string with '"),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, got := Tokenize(tt.path, tt.content)
+			_, got := Tokenize(&fileSet{}, tt.path, tt.content)
 
 			if got == nil {
 				t.Errorf("Tokenize() error = %v, want %v", got, tt.want)
@@ -542,3 +691,56 @@ func TestTokenizer_SyntaxErr(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenizer_ContinuesAfterErrors(t *testing.T) {
+	files := &fileSet{}
+	got, e := Tokenize(files, []string{"test.yz"}, "a '`b' c\n\"x\\zy\"\n'open")
+	if e == nil {
+		t.Errorf("Tokenize() error = nil, want errors")
+	}
+	want := []tokenAt{
+		{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "a"},
+		{pos: position{line: 1, col: 3}, tt: STRING_HEAD, data: ""},
+		{pos: position{line: 1, col: 5}, tt: IDENTIFIER, data: "b"},
+		{pos: position{line: 1, col: 6}, tt: STRING_TAIL, data: ""},
+		{pos: position{line: 1, col: 8}, tt: IDENTIFIER, data: "c"},
+		{pos: position{line: 1, col: 9}, tt: COMMA, data: "\n"},
+		{pos: position{line: 2, col: 1}, tt: STRING, data: "x\\zy"},
+		{pos: position{line: 2, col: 7}, tt: COMMA, data: "\n"},
+		{pos: position{line: 3, col: 1}, tt: STRING, data: "open"},
+		{pos: position{line: 3, col: 6}, tt: EOF, data: "EOF"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Tokenize() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if at(files, got[i]) != want[i] {
+			t.Errorf("Tokenize()[%d] = %v (pos: %v), want %v (pos: %v)", i, got[i], files.position(got[i].span), want[i], want[i].pos)
+		}
+	}
+}
+
+func TestTokenizer_SkipsInvalidBytes(t *testing.T) {
+	files := &fileSet{}
+	got, e := Tokenize(files, []string{"test.yz"}, "a\xc3 b\n\"x\xffy\"\nc")
+	if e == nil {
+		t.Errorf("Tokenize() error = nil, want errors")
+	}
+	want := []tokenAt{
+		{pos: position{line: 1, col: 1}, tt: IDENTIFIER, data: "a"},
+		{pos: position{line: 1, col: 4}, tt: IDENTIFIER, data: "b"},
+		{pos: position{line: 1, col: 5}, tt: COMMA, data: "\n"},
+		{pos: position{line: 2, col: 1}, tt: STRING, data: "xy"},
+		{pos: position{line: 2, col: 6}, tt: COMMA, data: "\n"},
+		{pos: position{line: 3, col: 1}, tt: IDENTIFIER, data: "c"},
+		{pos: position{line: 3, col: 2}, tt: EOF, data: "EOF"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Tokenize() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if at(files, got[i]) != want[i] {
+			t.Errorf("Tokenize()[%d] = %v (pos: %v), want %v (pos: %v)", i, got[i], files.position(got[i].span), want[i], want[i].pos)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package internal
 
+import "strings"
+
 type Kind int
 
 const (
@@ -9,6 +11,10 @@ const (
 	ARRAY
 	DICT
 	BOC
+	TUPLE
+	VARIANT
+	BOOL
+	GENERIC
 )
 
 type (
@@ -25,6 +31,10 @@ type (
 	StringType struct {
 		Type
 	}
+	// BoolType is the type of the comparisons, like a > 0.
+	BoolType struct {
+		Type
+	}
 	ArrayType struct {
 		elemType Type
 		Type
@@ -34,8 +44,42 @@ type (
 		valType Type
 		Type
 	}
+	// BocType is the type of a block: its variables and its result. The variables of a block
+	// signature without name are its result types: #(x Int, Int) takes x and returns an Int.
+	// A generic block or user-defined type has type parameters, an instance of a generic
+	// user-defined type has the types they take: Box(Int).
 	BocType struct {
-		variables []*Variable
+		name       string // name of a user-defined type, empty for the type of a block literal
+		variables  []*Variable
+		result     Type // type of the last expression of the boc, nil if it's unknown
+		typeParams []*GenericType
+		typeArgs   []Type
+		generic    *BocType // the declaration of the generic type of an instance
+		signature  bool     // declared with #(...), its named members are the parameters of the block
+		Type
+	}
+	// TupleType is the type of two or more expressions between parenthesis: (1, "a").
+	// A boc whose last expression is a tuple returns multiple results.
+	TupleType struct {
+		elemTypes []Type
+		Type
+	}
+
+	// VariantType is a sum type, its values are one of its cases: Option { Some(value T), None() }.
+	// Each case is a BocType with the variables it holds and the variant as its result.
+	VariantType struct {
+		name       string
+		cases      []*BocType
+		builtin    bool // the Option of a dictionary read, its Go types are generic
+		typeParams []*GenericType
+		typeArgs   []Type
+		generic    *VariantType // the declaration of the generic variant of an instance
+		Type
+	}
+	// GenericType is a type parameter of a generic type or block, a single uppercase letter: T.
+	// It takes the type of the value passed where the type is instantiated or the block is invoked.
+	GenericType struct {
+		name string
 		Type
 	}
 
@@ -43,3 +87,458 @@ type (
 		Type
 	}
 )
+
+func (t *IntType) String() string {
+	return "Int"
+}
+
+func (t *DecimalType) String() string {
+	return "Decimal"
+}
+
+func (t *StringType) String() string {
+	return "String"
+}
+
+func (t *BoolType) String() string {
+	return "Bool"
+}
+
+func (t *ArrayType) String() string {
+	return "[]" + t.elemType.String()
+}
+
+func (t *DictType) String() string {
+	return "[" + t.keyType.String() + ":" + t.valType.String() + "]"
+}
+
+func (t *BocType) String() string {
+	if t.name != "" {
+		return t.name + typeArgsString(t.typeArgs)
+	}
+	members := make([]string, 0, len(t.variables)+1)
+	signature := false // the result is one of the variables, without a name
+	for _, v := range t.variables {
+		members = append(members, strings.TrimSpace(v.name+" "+v.varType.String()))
+		signature = signature || v.name == ""
+	}
+	if t.result != nil && !signature {
+		members = append(members, t.result.String())
+	}
+	return "#(" + strings.Join(members, ", ") + ")"
+}
+
+func (t *TupleType) String() string {
+	elems := make([]string, len(t.elemTypes))
+	for i, e := range t.elemTypes {
+		elems[i] = e.String()
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+
+func (t *VariantType) String() string {
+	return t.name + typeArgsString(t.typeArgs)
+}
+
+func (t *GenericType) String() string {
+	return t.name
+}
+
+// instanceExample returns an instance of a generic type with Int as every type argument: Pair(Int, Int).
+func instanceExample(name string, params int) string {
+	return name + "(" + strings.TrimSuffix(strings.Repeat("Int, ", params), ", ") + ")"
+}
+
+// typeArgsString returns the type arguments of an instance between parenthesis: (Int, String).
+func typeArgsString(args []Type) string {
+	if len(args) == 0 {
+		return ""
+	}
+	names := make([]string, len(args))
+	for i, a := range args {
+		names[i] = a.String()
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+func (t *TBD) String() string {
+	return "TBD"
+}
+
+// assignable returns true if a value of type from can be stored in a variable of type to.
+// A type that is not known yet is assignable to and from any other type.
+func assignable(to, from Type) bool {
+	switch to := to.(type) {
+	case *TBD:
+		return true
+	case *IntType:
+		_, ok := from.(*IntType)
+		return ok || isTBD(from)
+	case *DecimalType:
+		_, ok := from.(*DecimalType)
+		return ok || isTBD(from)
+	case *StringType:
+		_, ok := from.(*StringType)
+		return ok || isTBD(from)
+	case *BoolType:
+		_, ok := from.(*BoolType)
+		return ok || isTBD(from)
+	case *ArrayType:
+		if from, ok := from.(*ArrayType); ok {
+			return assignable(to.elemType, from.elemType)
+		}
+	case *DictType:
+		if from, ok := from.(*DictType); ok {
+			return assignable(to.keyType, from.keyType) && assignable(to.valType, from.valType)
+		}
+	case *BocType:
+		// user-defined types are assignable only to themselves, with the same type arguments
+		if from, ok := from.(*BocType); ok {
+			return to.name == "" || to == from || to.name == from.name && assignableArgs(to.typeArgs, from.typeArgs)
+		}
+	case *VariantType:
+		if from, ok := from.(*VariantType); ok {
+			return to == from || to.name == from.name && assignableArgs(to.typeArgs, from.typeArgs)
+		}
+	case *GenericType:
+		// inside a generic declaration its values are only the ones of the type parameter
+		return to == from || isTBD(from)
+	case *TupleType:
+		if from, ok := from.(*TupleType); ok && len(from.elemTypes) == len(to.elemTypes) {
+			for i := range to.elemTypes {
+				if !assignable(to.elemTypes[i], from.elemTypes[i]) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return isTBD(from)
+}
+
+// assignableArgs returns true if the type arguments of two instances of a generic type are assignable.
+// A type without type arguments is the declaration of the type, it's checked where it's declared.
+func assignableArgs(to, from []Type) bool {
+	if len(to) == 0 || len(from) == 0 {
+		return true
+	}
+	for i := range to {
+		if !assignable(to[i], from[i]) || !assignable(from[i], to[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isComparable returns true if the values of type t can be compared with each other, like the keys of
+// a dictionary. Blocks, arrays, dictionaries and tuples can't, neither the types that hold them.
+// The type parameters can take any of them.
+func isComparable(t Type) bool {
+	return comparableType(t, map[Type]bool{})
+}
+
+func comparableType(t Type, seen map[Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *ArrayType, *DictType, *TupleType, *GenericType:
+		return false
+	case *BocType:
+		if t.name == "" {
+			return false
+		}
+		for _, v := range t.variables {
+			if !comparableType(v.varType, seen) {
+				return false
+			}
+		}
+	case *VariantType:
+		for _, c := range t.cases {
+			if !comparableType(c, seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isTBD(t Type) bool {
+	_, ok := t.(*TBD)
+	return ok
+}
+
+func isTuple(t Type) bool {
+	_, ok := t.(*TupleType)
+	return ok
+}
+
+// operatorType returns the type of an operator method of a basic type, like the + of an Int, or nil
+// if the type doesn't have it. The arithmetic operators return the type of the receiver and the
+// comparisons a Bool.
+func operatorType(t Type, operator string) *BocType {
+	var result Type
+	switch t.(type) {
+	case *IntType, *DecimalType:
+		switch operator {
+		case "+", "-", "*", "/", "%":
+			result = t
+		case "==", "!=", "<", ">", "<=", ">=":
+			result = new(BoolType)
+		}
+	case *StringType:
+		switch operator {
+		case "+":
+			result = t
+		case "==", "!=", "<", ">", "<=", ">=":
+			result = new(BoolType)
+		}
+	case *BoolType:
+		switch operator {
+		case "==", "!=", "&&", "||":
+			result = t
+		}
+	}
+	if result == nil {
+		return nil
+	}
+	bt := newBocType()
+	bt.variables = append(bt.variables, &Variable{span{}, "other", t})
+	bt.result = result
+	return bt
+}
+
+// optionType returns the built-in Option variant whose Some case holds a value of type t. It's the type
+// of a dictionary read, the key may not be in the dictionary: Option { Some(value T), None() }.
+func optionType(t Type) *VariantType {
+	vt := &VariantType{name: "Option", builtin: true}
+	vt.cases = []*BocType{
+		{name: "Some", variables: []*Variable{{name: "value", varType: t}}, result: vt},
+		{name: "None", variables: []*Variable{}, result: vt},
+	}
+	return vt
+}
+
+// typeArguments returns the types the type parameters of a generic type take: the type arguments of
+// an instance, or the type parameters themselves for the declaration.
+func typeArguments(params []*GenericType, args []Type) []Type {
+	if args != nil {
+		return args
+	}
+	types := make([]Type, len(params))
+	for i, tp := range params {
+		types[i] = tp
+	}
+	return types
+}
+
+// bindings are the types the type parameters take in an instance of a generic type or in an
+// invocation of a generic block. A type parameter not bound yet is nil.
+type bindings map[*GenericType]Type
+
+// newBindings returns the bindings of the type parameters, none of them bound yet.
+func newBindings(params []*GenericType) bindings {
+	b := bindings{}
+	for _, tp := range params {
+		b[tp] = nil
+	}
+	return b
+}
+
+// infer binds the type parameters in the type of a member to the types they have in arg, the type of
+// the value passed: the member value T and the argument 1 bind T to Int. A type parameter keeps the
+// first type it's bound to.
+func (b bindings) infer(member, arg Type) {
+	switch m := member.(type) {
+	case *GenericType:
+		if bound, ok := b[m]; ok && bound == nil && arg != nil && !isTBD(arg) {
+			b[m] = arg
+		}
+	case *ArrayType:
+		if a, ok := arg.(*ArrayType); ok {
+			b.infer(m.elemType, a.elemType)
+		}
+	case *DictType:
+		if a, ok := arg.(*DictType); ok {
+			b.infer(m.keyType, a.keyType)
+			b.infer(m.valType, a.valType)
+		}
+	case *TupleType:
+		if a, ok := arg.(*TupleType); ok && len(a.elemTypes) == len(m.elemTypes) {
+			for i := range m.elemTypes {
+				b.infer(m.elemTypes[i], a.elemTypes[i])
+			}
+		}
+	case *VariantType:
+		if a, ok := arg.(*VariantType); ok && a.name == m.name && len(a.typeArgs) > 0 {
+			for i, t := range typeArguments(m.typeParams, m.typeArgs) {
+				b.infer(t, a.typeArgs[i])
+			}
+		}
+	case *BocType:
+		a, ok := arg.(*BocType)
+		if !ok {
+			return
+		}
+		if m.name != "" {
+			if a.name == m.name && len(a.typeArgs) > 0 {
+				for i, t := range typeArguments(m.typeParams, m.typeArgs) {
+					b.infer(t, a.typeArgs[i])
+				}
+			}
+			return
+		}
+		for i := 0; i < len(m.variables) && i < len(a.variables); i++ {
+			b.infer(m.variables[i].varType, a.variables[i].varType)
+		}
+		if m.result != nil {
+			b.infer(m.result, a.result)
+		}
+	}
+}
+
+// resolve binds the type parameters that weren't inferred to TBD, they have to be taken from
+// somewhere else, like the type of the variable the value is assigned to.
+func (b bindings) resolve() bindings {
+	for tp, t := range b {
+		if t == nil {
+			b[tp] = newTBD()
+		}
+	}
+	return b
+}
+
+// substitute returns t with the type parameters replaced by the types bound to them. The generic
+// user-defined types and variants in t become instances: with T bound to Int, Option is Option(Int).
+func (b bindings) substitute(t Type) Type {
+	return (&substitution{b, map[string]Type{}}).of(t)
+}
+
+// substitution replaces the type parameters of a type. The instances created are kept by name and
+// type arguments, so a recursive type refers to the instance being created.
+type substitution struct {
+	bindings  bindings
+	instances map[string]Type
+}
+
+func (s *substitution) of(t Type) Type {
+	switch t := t.(type) {
+	case *GenericType:
+		if bound, ok := s.bindings[t]; ok && bound != nil {
+			return bound
+		}
+	case *ArrayType:
+		return &ArrayType{elemType: s.of(t.elemType)}
+	case *DictType:
+		return &DictType{keyType: s.of(t.keyType), valType: s.of(t.valType)}
+	case *TupleType:
+		return &TupleType{elemTypes: s.types(t.elemTypes)}
+	case *VariantType:
+		if len(t.typeParams) == 0 && len(t.typeArgs) == 0 {
+			return t
+		}
+		declaration := t
+		if t.generic != nil {
+			declaration = t.generic
+		}
+		return s.variant(declaration, s.types(typeArguments(t.typeParams, t.typeArgs)))
+	case *BocType:
+		if vt, ok := t.result.(*VariantType); ok && isCaseOf(t, vt) {
+			// a case of a generic variant is the case of the instance of the variant
+			return caseNamed(s.of(vt).(*VariantType), t.name)
+		}
+		if t.name == "" {
+			bt := &BocType{variables: s.variables(t.variables), typeParams: t.typeParams, signature: t.signature}
+			if t.result != nil {
+				bt.result = s.of(t.result)
+			}
+			return bt
+		}
+		if len(t.typeParams) == 0 && len(t.typeArgs) == 0 {
+			return t
+		}
+		declaration := t
+		if t.generic != nil {
+			declaration = t.generic
+		}
+		return s.boc(declaration, s.types(typeArguments(t.typeParams, t.typeArgs)))
+	}
+	return t
+}
+
+func (s *substitution) types(types []Type) []Type {
+	result := make([]Type, len(types))
+	for i, t := range types {
+		result[i] = s.of(t)
+	}
+	return result
+}
+
+func (s *substitution) variables(variables []*Variable) []*Variable {
+	result := make([]*Variable, len(variables))
+	for i, v := range variables {
+		result[i] = &Variable{v.span, v.name, s.of(v.varType)}
+	}
+	return result
+}
+
+// variant returns the instance of a generic variant with the type arguments, its cases hold the
+// values of the types of the arguments.
+func (s *substitution) variant(declaration *VariantType, args []Type) *VariantType {
+	key := declaration.name + typeArgsString(args)
+	if t, ok := s.instances[key]; ok {
+		return t.(*VariantType)
+	}
+	vt := &VariantType{name: declaration.name, typeArgs: args, generic: declaration}
+	s.instances[key] = vt
+	inner := &substitution{declaration.bind(args), s.instances}
+	for _, c := range declaration.cases {
+		vt.cases = append(vt.cases, &BocType{name: c.name, variables: inner.variables(c.variables), result: vt})
+	}
+	return vt
+}
+
+// boc returns the instance of a generic user-defined type with the type arguments.
+func (s *substitution) boc(declaration *BocType, args []Type) *BocType {
+	key := declaration.name + typeArgsString(args)
+	if t, ok := s.instances[key]; ok {
+		return t.(*BocType)
+	}
+	bt := &BocType{name: declaration.name, typeArgs: args, generic: declaration}
+	s.instances[key] = bt
+	inner := &substitution{declaration.bind(args), s.instances}
+	bt.variables = inner.variables(declaration.variables)
+	if declaration.result != nil {
+		bt.result = inner.of(declaration.result)
+	}
+	return bt
+}
+
+// bind returns the bindings of the type parameters of the variant to the type arguments.
+func (t *VariantType) bind(args []Type) bindings {
+	b := bindings{}
+	for i, tp := range t.typeParams {
+		b[tp] = args[i]
+	}
+	return b
+}
+
+// bind returns the bindings of the type parameters of the type to the type arguments.
+func (t *BocType) bind(args []Type) bindings {
+	b := bindings{}
+	for i, tp := range t.typeParams {
+		b[tp] = args[i]
+	}
+	return b
+}
+
+// caseNamed returns the case of the variant with the name, or nil if it doesn't have it.
+func caseNamed(vt *VariantType, name string) *BocType {
+	for _, c := range vt.cases {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}